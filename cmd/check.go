@@ -19,26 +19,37 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/cqroot/minop/pkg/executor"
 	"github.com/spf13/cobra"
 )
 
-var checkBulletStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("12"))
+var (
+	checkBulletStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("12"))
+	checkSourceStyle = lipgloss.NewStyle().Faint(true)
+)
 
-// RunCheckCmd loads and validates the configuration, then prints task info.
+// RunCheckCmd loads and validates the configuration, then prints the tasks,
+// with included task files expanded, and where each task is defined.
 func RunCheckCmd(cmd *cobra.Command, args []string) {
 	e := executor.New(
 		executor.WithVerboseLevel(flagVerboseLevel),
 		executor.WithMaxProcs(flagMaxProcs))
 
-	_, ops, err := e.LoadConfig(flagConfigFile)
+	pb, err := e.LoadConfig(flagConfigFile)
 	CheckErr(err)
 
 	fmt.Println()
-	for _, op := range ops {
-		fmt.Printf("  %s %s\n", checkBulletStyle.Render("•"), op.DefaultName())
+	for _, play := range pb.Plays {
+		if play.Name != "" {
+			fmt.Printf("  %s  %s\n", play.Name, checkSourceStyle.Render("hosts: "+strings.Join(play.Hosts, ", ")))
+		}
+		for _, op := range play.Operations {
+			fmt.Printf("  %s %s  %s\n", checkBulletStyle.Render("•"), op.DefaultName(),
+				checkSourceStyle.Render(op.Source()))
+		}
 	}
 }
 
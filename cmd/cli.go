@@ -30,7 +30,8 @@ func RunCliCmd(cmd *cobra.Command, args []string) {
 		flagCliConfigFile = "./minop.yaml"
 	}
 
-	c := cli.New(cli.WithConfigFile(flagCliConfigFile), cli.WithMaxProcs(flagMaxProcs))
+	c := cli.New(cli.WithConfigFile(flagCliConfigFile), cli.WithMaxProcs(flagMaxProcs),
+		cli.WithMaxSessions(flagMaxSessions))
 	CheckErr(c.Run())
 }
 
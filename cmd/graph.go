@@ -0,0 +1,93 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/cqroot/minop/pkg/executor"
+	"github.com/spf13/cobra"
+)
+
+var flagGraphFormat string
+
+var (
+	graphBulletStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("12"))
+	graphKeyStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("14"))
+	graphDimStyle    = lipgloss.NewStyle().Faint(true)
+)
+
+// RunGraphCmd prints the dependency graph of the tasks of each play, as text
+// or in the DOT language.
+func RunGraphCmd(cmd *cobra.Command, args []string) {
+	e := executor.New(
+		executor.WithVerboseLevel(flagVerboseLevel),
+		executor.WithMaxProcs(flagMaxProcs))
+
+	pb, err := e.LoadConfig(flagConfigFile)
+	CheckErr(err)
+
+	switch flagGraphFormat {
+	case "dot":
+		CheckErr(pb.WriteDot(os.Stdout))
+	case "text":
+		printGraph(pb)
+	default:
+		CheckErr(fmt.Errorf("unknown format %q, expected text or dot", flagGraphFormat))
+	}
+}
+
+// printGraph prints each task with the tasks it depends on.
+func printGraph(pb *executor.Playbook) {
+	fmt.Println()
+	for _, play := range pb.Plays {
+		if play.Name != "" {
+			fmt.Printf("  %s  %s\n", play.Name, graphDimStyle.Render("hosts: "+strings.Join(play.Hosts, ", ")))
+		}
+
+		g := play.Graph
+		for i, op := range g.Operations {
+			deps := make([]string, len(g.Deps[i]))
+			for k, j := range g.Deps[i] {
+				deps[k] = g.Key(j)
+			}
+
+			line := fmt.Sprintf("  %s %s %s", graphBulletStyle.Render("•"), graphKeyStyle.Render(g.Key(i)), op.DefaultName())
+			if len(deps) > 0 {
+				line += "  " + graphDimStyle.Render("← "+strings.Join(deps, ", "))
+			}
+			fmt.Println(line)
+		}
+	}
+}
+
+// NewGraphCmd creates the graph command that shows the task dependency graph.
+func NewGraphCmd() *cobra.Command {
+	c := cobra.Command{
+		Use:   "graph",
+		Short: "Show task dependency graph",
+		Long:  "Show the dependency graph of the tasks, as text or in the DOT language for Graphviz.",
+		Run:   RunGraphCmd,
+	}
+	c.Flags().StringVar(&flagGraphFormat, "format", "text", "Output format: text or dot")
+
+	return &c
+}
@@ -41,8 +41,9 @@ func RunHostCmd(cmd *cobra.Command, args []string) {
 		executor.WithVerboseLevel(flagVerboseLevel),
 		executor.WithMaxProcs(flagMaxProcs))
 
-	hostGroup, _, err := e.LoadConfig(flagConfigFile)
+	pb, err := e.LoadConfig(flagConfigFile)
 	CheckErr(err)
+	hostGroup := pb.HostGroup
 
 	groups := make([]string, 0, len(hostGroup))
 	for group := range hostGroup {
@@ -62,7 +63,7 @@ func RunHostCmd(cmd *cobra.Command, args []string) {
 			}
 			_, _ = fmt.Fprintf(os.Stdout, "%s %s\n",
 				branch,
-				hostStyle.Render(fmt.Sprintf("%s@%s:%d", host.User, host.Address, host.Port)))
+				hostStyle.Render(host.String()))
 		}
 		if idx < len(groups)-1 {
 			_, _ = fmt.Fprintln(os.Stdout)
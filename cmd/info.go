@@ -28,9 +28,10 @@ import (
 var labelStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("12"))
 
 func RunInfoCmd(cmd *cobra.Command, args []string) {
-	fmt.Printf("    %s    %s\n", labelStyle.Render("Config"), viper.ConfigFileUsed())
-	fmt.Printf("    %s  %d\n", labelStyle.Render("MaxProcs"), flagMaxProcs)
-	fmt.Printf("    %s   %d\n", labelStyle.Render("Verbose"), flagVerboseLevel)
+	fmt.Printf("    %s       %s\n", labelStyle.Render("Config"), viper.ConfigFileUsed())
+	fmt.Printf("    %s     %d\n", labelStyle.Render("MaxProcs"), flagMaxProcs)
+	fmt.Printf("    %s  %d\n", labelStyle.Render("MaxSessions"), flagMaxSessions)
+	fmt.Printf("    %s      %d\n", labelStyle.Render("Verbose"), flagVerboseLevel)
 }
 
 func NewInfoCmd() *cobra.Command {
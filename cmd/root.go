@@ -22,6 +22,7 @@ import (
 
 	"github.com/cqroot/minop/pkg/executor"
 	"github.com/cqroot/minop/pkg/logs"
+	"github.com/cqroot/minop/pkg/remote"
 	"github.com/cqroot/minop/pkg/version"
 	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
@@ -31,7 +32,13 @@ import (
 var (
 	flagConfigFile   string
 	flagMaxProcs     int
+	flagMaxSessions  int
 	flagVerboseLevel int
+	flagExtraVars    []string
+	flagTags         []string
+	flagSkipTags     []string
+	flagCheck        bool
+	flagDiff         bool
 )
 
 // CheckErr logs the error and exits if err is not nil.
@@ -53,6 +60,11 @@ func initConfig(cmd *cobra.Command) error {
 	}
 	flagMaxProcs = viper.GetInt("max-procs")
 
+	if err := viper.BindPFlag("max-sessions", cmd.Flags().Lookup("max-sessions")); err != nil {
+		return err
+	}
+	flagMaxSessions = viper.GetInt("max-sessions")
+
 	if err := viper.BindPFlag("verbose", cmd.Flags().Lookup("verbose")); err != nil {
 		return err
 	}
@@ -75,6 +87,7 @@ func PersistentPreRunE(cmd *cobra.Command, args []string) error {
 	logs.Logger().Debug().
 		Str("config_file", flagConfigFile).
 		Int("max_procs", flagMaxProcs).
+		Int("max_sessions", flagMaxSessions).
 		Int("verbose_level", flagVerboseLevel).
 		Str("log_level", logs.Logger().GetLevel().String()).
 		Msg("run root command")
@@ -84,14 +97,23 @@ func PersistentPreRunE(cmd *cobra.Command, args []string) error {
 
 // RunRootCmd is the default root command that executes all operations.
 func RunRootCmd(cmd *cobra.Command, args []string) {
+	extraVars, err := executor.ParseExtraVars(flagExtraVars)
+	CheckErr(err)
+
 	e := executor.New(
 		executor.WithVerboseLevel(flagVerboseLevel),
-		executor.WithMaxProcs(flagMaxProcs))
-
-	hostGroup, ops, err := e.LoadConfig(flagConfigFile)
+		executor.WithMaxProcs(flagMaxProcs),
+		executor.WithMaxSessions(flagMaxSessions),
+		executor.WithExtraVars(extraVars),
+		executor.WithTags(flagTags),
+		executor.WithSkipTags(flagSkipTags),
+		executor.WithCheck(flagCheck),
+		executor.WithDiff(flagDiff))
+
+	pb, err := e.LoadConfig(flagConfigFile)
 	CheckErr(err)
 
-	err = e.ExecuteOperations(hostGroup, ops)
+	err = e.ExecuteOperations(pb)
 	CheckErr(err)
 }
 
@@ -106,12 +128,19 @@ func NewRootCmd() *cobra.Command {
 	}
 	c.PersistentFlags().StringVarP(&flagConfigFile, "config", "c", "", "Specify config file (default ./minop.yaml)")
 	c.PersistentFlags().IntVarP(&flagMaxProcs, "max-procs", "p", 1, "Maximum number of tasks to execute simultaneously (default 1)")
+	c.PersistentFlags().IntVar(&flagMaxSessions, "max-sessions", remote.DefaultMaxSessions, "Maximum number of concurrent SSH sessions per host, should stay below sshd's MaxSessions")
+	c.Flags().StringArrayVarP(&flagExtraVars, "extra-vars", "e", nil, "Set extra variables as <name>=<value> or @<file>, taking precedence over the config file (repeatable)")
+	c.Flags().BoolVar(&flagCheck, "check", false, "Check mode: report the changes tasks would make without making them")
+	c.Flags().BoolVar(&flagDiff, "diff", false, "Show the changes copy and template tasks make to remote files")
+	c.PersistentFlags().StringSliceVarP(&flagTags, "tags", "t", nil, "Only run tasks with any of these tags (comma-separated, repeatable)")
+	c.PersistentFlags().StringSliceVar(&flagSkipTags, "skip-tags", nil, "Skip tasks with any of these tags (comma-separated, repeatable)")
 	c.PersistentFlags().CountVarP(&flagVerboseLevel, "verbose", "v", "Increase output verbosity. Use multiple v's for more detail, e.g., -v, -vv (default 0)")
 
 	c.AddCommand(NewHostCmd())
 	c.AddCommand(NewTaskCmd())
 	c.AddCommand(NewInfoCmd())
 	c.AddCommand(NewCheckCmd())
+	c.AddCommand(NewGraphCmd())
 	c.AddCommand(NewCliCmd())
 	c.Version = version.Get().String()
 	return &c
@@ -19,26 +19,53 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/cqroot/minop/pkg/executor"
+	"github.com/cqroot/minop/pkg/operation"
 	"github.com/spf13/cobra"
 )
 
-var taskBulletStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("12"))
+var (
+	taskBulletStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("12"))
+	taskDimStyle    = lipgloss.NewStyle().Faint(true)
+)
 
-// RunTaskCmd displays all tasks from the configuration file.
+// RunTaskCmd displays all tasks from the configuration file with their tags.
+// Tasks left out by --tags and --skip-tags are dimmed.
 func RunTaskCmd(cmd *cobra.Command, args []string) {
 	e := executor.New(
 		executor.WithVerboseLevel(flagVerboseLevel),
-		executor.WithMaxProcs(flagMaxProcs))
+		executor.WithMaxProcs(flagMaxProcs),
+		executor.WithTags(flagTags),
+		executor.WithSkipTags(flagSkipTags))
 
-	_, ops, err := e.LoadConfig(flagConfigFile)
+	pb, err := e.LoadConfig(flagConfigFile)
 	CheckErr(err)
 
 	fmt.Println()
+	for _, play := range pb.Plays {
+		if play.Name != "" {
+			fmt.Printf("  %s  %s\n", play.Name, taskDimStyle.Render("hosts: "+strings.Join(play.Hosts, ", ")))
+		}
+		printTasks(e, play.Operations)
+	}
+}
+
+// printTasks prints the tasks with their tags, dimming those not selected.
+func printTasks(e *executor.Executor, ops []operation.Operation) {
 	for _, op := range ops {
-		fmt.Printf("  %s %s\n", taskBulletStyle.Render("•"), op.DefaultName())
+		tags := ""
+		if len(op.Tags()) > 0 {
+			tags = " " + taskDimStyle.Render("["+strings.Join(op.Tags(), ", ")+"]")
+		}
+
+		if e.Selected(op) {
+			fmt.Printf("  %s %s%s\n", taskBulletStyle.Render("•"), op.DefaultName(), tags)
+		} else {
+			fmt.Printf("  %s%s\n", taskDimStyle.Render("◦ "+op.DefaultName()), tags)
+		}
 	}
 }
 
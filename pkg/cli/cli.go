@@ -20,6 +20,8 @@ package cli
 import (
 	"errors"
 	"fmt"
+	"os"
+	"path"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
@@ -41,6 +43,7 @@ type Cli struct {
 	configFile      string
 	optVerboseLevel int
 	optMaxProcs     int
+	optMaxSessions  int
 }
 
 // New creates a new Cli instance with the given options.
@@ -48,6 +51,7 @@ func New(opts ...Option) *Cli {
 	c := Cli{
 		optVerboseLevel: 0,
 		optMaxProcs:     1,
+		optMaxSessions:  remote.DefaultMaxSessions,
 	}
 
 	for _, opt := range opts {
@@ -86,6 +90,10 @@ func ShowHelp() {
 	helpEntries.Put("exit", "Quit minop")
 	helpEntries.Put("quit", "Quit minop")
 	helpEntries.Put("help", "Show help output")
+	helpEntries.Put("cd DIR", "Run the following commands in DIR, or in the login directory if DIR is omitted")
+	helpEntries.Put("export NAME=VALUE", "Set an environment variable for the following commands, or list them")
+	helpEntries.Put("unset NAME", "Remove an environment variable set with export")
+	helpEntries.Put("pipe FILE COMMAND", "Run COMMAND with the content of the local FILE as its standard input")
 	_ = helpEntries.ForEach(func(k, v string) error {
 		fmt.Printf("    %s    %s\n", helpKeyStyle.Render(k), helpValStyle.Render(v))
 		return nil
@@ -94,6 +102,65 @@ func ShowHelp() {
 	fmt.Println()
 }
 
+// session holds the settings applied to every command of a CLI session.
+type session struct {
+	env   map[string]string
+	chdir string
+}
+
+// resolveDir returns the directory "cd arg" changes to from dir, like a shell
+// would on the remote hosts. Since commands start in the login directory, it
+// is represented by "" and paths below it are kept relative: "~/app" becomes
+// "app".
+func resolveDir(dir, arg string) string {
+	switch {
+	case arg == "" || arg == "~":
+		return ""
+	case strings.HasPrefix(arg, "~/"):
+		dir, arg = "", arg[2:]
+	case path.IsAbs(arg):
+		dir = ""
+	}
+
+	dir = path.Join(dir, arg)
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// handleBuiltin applies the session commands cd, export and unset.
+// It reports whether cmd was one of them.
+func (s *session) handleBuiltin(cmd string) (bool, error) {
+	name, arg, _ := strings.Cut(cmd, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch name {
+	case "cd":
+		s.chdir = resolveDir(s.chdir, arg)
+	case "export":
+		if arg == "" {
+			for k, v := range s.env {
+				fmt.Printf("    %s=%s\n", helpKeyStyle.Render(k), v)
+			}
+			return true, nil
+		}
+		k, v, ok := strings.Cut(arg, "=")
+		if !ok {
+			return true, fmt.Errorf("usage: export NAME=VALUE")
+		}
+		if err := remote.ValidateEnvName(k); err != nil {
+			return true, err
+		}
+		s.env[k] = v
+	case "unset":
+		delete(s.env, arg)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
 // Run starts the interactive CLI loop, reading commands from stdin and
 // executing them on the configured remote hosts. It returns when the user
 // quits or encounters an error.
@@ -103,12 +170,16 @@ func (c Cli) Run() error {
 		configFile = defaultConfigFile
 	}
 
-	e := executor.New(executor.WithMaxProcs(c.optMaxProcs))
-	hostGroup, _, err := e.LoadConfig(configFile)
+	e := executor.New(
+		executor.WithMaxProcs(c.optMaxProcs),
+		executor.WithMaxSessions(c.optMaxSessions))
+	pb, err := e.LoadConfig(configFile)
 	if err != nil {
 		return err
 	}
-	pool := remote.NewHostPool()
+	pool := e.NewHostPool()
+	defer func() { _ = pool.Close() }()
+	sess := session{env: make(map[string]string)}
 
 	for {
 		val, err := prompt.New(prompt.WithTheme(MinopTheme)).Ask("MINOP").
@@ -136,15 +207,40 @@ func (c Cli) Run() error {
 			continue
 		}
 
-		op, err := operation.NewOpShell(operation.Input{
+		if ok, err := sess.handleBuiltin(trimmed); ok {
+			if err != nil {
+				fmt.Println(err)
+			}
+			continue
+		}
+
+		in := operation.Input{
 			Shell: val,
-		})
+			Env:   sess.env,
+			Chdir: sess.chdir,
+		}
+		if name, arg, _ := strings.Cut(trimmed, " "); name == "pipe" {
+			file, cmd, ok := strings.Cut(strings.TrimSpace(arg), " ")
+			if !ok || strings.TrimSpace(cmd) == "" {
+				fmt.Println("usage: pipe FILE COMMAND")
+				continue
+			}
+			if _, err := os.Stat(file); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			in.Shell = strings.TrimSpace(cmd)
+			in.StdinFile = file
+		}
+
+		op, err := operation.NewOpShell(in)
 		if err != nil {
 			return err
 		}
 		op.SetRole(constants.RoleAll)
+		op.SetRaw(true)
 
-		err = e.ExecuteOperation(hostGroup, pool, op)
+		err = e.ExecuteOperation(pb, pool, op)
 		if err != nil {
 			return err
 		}
@@ -36,6 +36,15 @@ func WithMaxProcs(maxProcs int) Option {
 	}
 }
 
+// WithMaxSessions sets the maximum number of concurrent SSH sessions per host.
+func WithMaxSessions(maxSessions int) Option {
+	return func(c *Cli) {
+		if maxSessions > 0 {
+			c.optMaxSessions = maxSessions
+		}
+	}
+}
+
 // WithConfigFile sets the path to the configuration file.
 func WithConfigFile(configFile string) Option {
 	return func(c *Cli) {
@@ -0,0 +1,232 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package diff computes line-based unified diffs of text.
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Context is the number of unchanged lines shown around each change.
+const Context = 3
+
+// edit is a line of a diff: kept (' '), deleted ('-') or inserted ('+').
+type edit struct {
+	kind byte
+	line string
+}
+
+// IsText reports whether data looks like text that can be diffed line by
+// line: valid UTF-8 without NUL bytes.
+func IsText(data []byte) bool {
+	return utf8.Valid(data) && bytes.IndexByte(data, 0) == -1
+}
+
+// Unified returns the unified diff turning from into to, with the file names
+// fromName and toName in its header, or "" if they are equal. Lines missing
+// a final newline are marked as in diff(1).
+func Unified(fromName, toName, from, to string) string {
+	if from == to {
+		return ""
+	}
+	edits := lineEdits(splitLines(from), splitLines(to))
+
+	// Group the changes into hunks with their context, merging hunks whose
+	// contexts overlap or touch.
+	var hunks [][2]int
+	for i, e := range edits {
+		if e.kind == ' ' {
+			continue
+		}
+		lo, hi := max(0, i-Context), min(len(edits), i+Context+1)
+		if n := len(hunks); n > 0 && lo <= hunks[n-1][1] {
+			hunks[n-1][1] = hi
+		} else {
+			hunks = append(hunks, [2]int{lo, hi})
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	fromLine, toLine, next := 0, 0, 0
+	for _, h := range hunks {
+		for ; next < h[0]; next++ {
+			fromLine, toLine = advance(edits[next], fromLine, toLine)
+		}
+
+		fromCount, toCount := 0, 0
+		for _, e := range edits[h[0]:h[1]] {
+			fromCount, toCount = advance(e, fromCount, toCount)
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(fromLine, fromCount), hunkRange(toLine, toCount))
+
+		for ; next < h[1]; next++ {
+			e := edits[next]
+			sb.WriteByte(e.kind)
+			sb.WriteString(e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+			fromLine, toLine = advance(e, fromLine, toLine)
+		}
+	}
+	return sb.String()
+}
+
+// advance returns the line counts of both sides after the edit.
+func advance(e edit, from, to int) (int, int) {
+	if e.kind != '+' {
+		from++
+	}
+	if e.kind != '-' {
+		to++
+	}
+	return from, to
+}
+
+// hunkRange formats the range of a hunk on one side, which starts after the
+// first start lines and spans count lines.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// splitLines splits s into lines, keeping their newlines.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// maxEdits bounds the work of lineEdits. Inputs that differ in more lines
+// than this are diffed as a whole replacement of their differing middle.
+const maxEdits = 1000
+
+// lineEdits returns an edit script turning a into b, a shortest one unless
+// they differ in more than maxEdits lines.
+func lineEdits(a, b []string) []edit {
+	var prefix, suffix []edit
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		prefix = append(prefix, edit{' ', a[0]})
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		suffix = append(suffix, edit{' ', a[len(a)-1]})
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+
+	edits, ok := myers(a, b)
+	if !ok {
+		edits = edits[:0]
+		for _, line := range a {
+			edits = append(edits, edit{'-', line})
+		}
+		for _, line := range b {
+			edits = append(edits, edit{'+', line})
+		}
+	}
+
+	edits = append(prefix, edits...)
+	for i := len(suffix) - 1; i >= 0; i-- {
+		edits = append(edits, suffix[i])
+	}
+	return edits
+}
+
+// myers returns a shortest edit script turning a into b, computed with
+// Myers' algorithm, or false if it is longer than maxEdits.
+func myers(a, b []string) ([]edit, bool) {
+	n, m := len(a), len(b)
+	limit := min(n+m, maxEdits)
+
+	// v[k+limit+1] is the furthest x reached on diagonal k = x - y. trace
+	// holds the diagonals -d to d of v before each step d, to walk the path back.
+	offset := limit + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+	found := false
+	for d := 0; d <= limit && !found; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		return nil, false
+	}
+
+	var edits []edit
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		tv := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && tv[k-1+d] < tv[k+1+d]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := tv[prevK+d]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, edit{' ', a[x]})
+		}
+		if x == prevX {
+			y--
+			edits = append(edits, edit{'+', b[y]})
+		} else {
+			x--
+			edits = append(edits, edit{'-', a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		edits = append(edits, edit{' ', a[x]})
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits, true
+}
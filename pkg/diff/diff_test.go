@@ -0,0 +1,75 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package diff_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cqroot/minop/pkg/diff"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnified(t *testing.T) {
+	require.Equal(t, "", diff.Unified("a", "b", "x\n", "x\n"))
+
+	from := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	to := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n"
+	require.Equal(t, `--- a
++++ b
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -10,3 +10,4 @@
+ 10
+ 11
+ 12
++13
+`, diff.Unified("a", "b", from, to))
+
+	require.Equal(t, `--- /dev/null
++++ b
+@@ -0,0 +1,1 @@
++x
+\ No newline at end of file
+`, diff.Unified("/dev/null", "b", "", "x"))
+
+	// Inputs differing in too many lines are diffed as a whole replacement.
+	var many, other strings.Builder
+	for i := range 3000 {
+		many.WriteString("a\n")
+		if i%2 == 0 {
+			other.WriteString("b\n")
+		} else {
+			other.WriteString("a\n")
+		}
+	}
+	out := diff.Unified("a", "b", many.String(), other.String())
+	require.Contains(t, out, "@@ -1,3000 +1,3000 @@\n")
+}
+
+func TestIsText(t *testing.T) {
+	require.True(t, diff.IsText([]byte("port: 80\n")))
+	require.False(t, diff.IsText([]byte{0x7f, 'E', 'L', 'F', 0}))
+	require.False(t, diff.IsText([]byte{0xff, 0xfe}))
+}
@@ -0,0 +1,66 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package executor
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cqroot/minop/pkg/operation"
+	"github.com/cqroot/minop/pkg/remote"
+)
+
+// delegateHost returns the host a task delegated to name runs on: the
+// control machine for "local", the first host of the group if name is a
+// role, or else the host of the configuration file whose address or host
+// string is name.
+func (pb *Playbook) delegateHost(name string) (remote.Host, error) {
+	if name == remote.LocalHost {
+		return remote.NewLocalHost(), nil
+	}
+	if hosts := pb.inventory[name]; len(hosts) > 0 {
+		return hosts[0], nil
+	}
+
+	roles := make([]string, 0, len(pb.inventory))
+	for role := range pb.inventory {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	for _, role := range roles {
+		for _, h := range pb.inventory[role] {
+			if h.Address == name || h.String() == name {
+				return h, nil
+			}
+		}
+	}
+	return remote.Host{}, fmt.Errorf("unknown host %q", name)
+}
+
+// validateDelegates checks that the operations are delegated to known hosts.
+func (pb *Playbook) validateDelegates(ops []operation.Operation) error {
+	for _, op := range ops {
+		if op.DelegateTo() == "" {
+			continue
+		}
+		if _, err := pb.delegateHost(op.DelegateTo()); err != nil {
+			return fmt.Errorf("task %q: delegate_to: %w", op.Name(), err)
+		}
+	}
+	return nil
+}
@@ -22,7 +22,9 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
@@ -38,17 +40,34 @@ import (
 // Output styles for terminal formatting
 var (
 	labelStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("14"))
+	playStyle      = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("13"))
 	taskStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
 	dimStyle       = lipgloss.NewStyle().Faint(true)
 	hostStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("12"))
 	timestampStyle = lipgloss.NewStyle().Faint(true)
+	diffAddStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+	diffDelStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	diffHunkStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("14"))
 )
 
 // Executor orchestrates remote operations across multiple hosts.
 type Executor struct {
 	optVerboseLevel int
 	optMaxProcs     int
+	optMaxSessions  int
+	optDialer       remote.Dialer
+	optExtraVars    operation.Vars
+	optTags         []string
+	optSkipTags     []string
+	optCheck        bool
+	optDiff         bool
+	become          bool // Whether commands of the current play run through sudo
 	outputPrefix    string
+	registered      *registry
+	recap           *recap
+	notified        *notifications
+	onceRuns        *onceResults
+	failures        *hostFailures // Failed hosts, if failures do not stop the play
 }
 
 // New creates a new Executor with the given options.
@@ -56,6 +75,10 @@ func New(opts ...Option) *Executor {
 	e := Executor{
 		optVerboseLevel: 0,
 		optMaxProcs:     1,
+		optMaxSessions:  remote.DefaultMaxSessions,
+		registered:      newRegistry(),
+		recap:           newRecap(),
+		notified:        newNotifications(),
 	}
 
 	for _, opt := range opts {
@@ -84,44 +107,205 @@ func (e Executor) printValue(key string, val string) {
 	}
 }
 
+// printResult outputs the fields of an operation result. Whether the host
+// changed is shown next to the host instead.
+func (e Executor) printResult(res *gtypes.OrderedMap[string, string]) {
+	if res == nil {
+		return
+	}
+	_ = res.ForEach(func(key, val string) error {
+		switch key {
+		case operation.ResultChanged:
+		case operation.ResultDiff:
+			e.printDiff(val)
+		default:
+			e.printValue(key, val)
+		}
+		return nil
+	})
+}
+
+// printDiff outputs a unified diff block-style, with added and deleted lines
+// and hunk headers colored.
+func (e Executor) printDiff(val string) {
+	fmt.Printf("%s    %s:\n", e.outputPrefix, labelStyle.Render(operation.ResultDiff))
+	scanner := bufio.NewScanner(strings.NewReader(val))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "+++ "), strings.HasPrefix(line, "--- "):
+			line = dimStyle.Render(line)
+		case strings.HasPrefix(line, "@@"):
+			line = diffHunkStyle.Render(line)
+		case strings.HasPrefix(line, "+"):
+			line = diffAddStyle.Render(line)
+		case strings.HasPrefix(line, "-"):
+			line = diffDelStyle.Render(line)
+		}
+		fmt.Printf("%s        %s\n", e.outputPrefix, line)
+	}
+}
+
+// printAttempts outputs the number of attempts of a retried operation and,
+// at higher verbosity, the result of each unsuccessful attempt.
+func (e Executor) printAttempts(attempts []attempt) {
+	if len(attempts) == 0 {
+		return
+	}
+
+	e.printValue("Attempts", strconv.Itoa(len(attempts)+1))
+	if e.optVerboseLevel == 0 {
+		return
+	}
+
+	ae := e
+	ae.outputPrefix += "    "
+	for i, a := range attempts {
+		fmt.Printf("%s    %s\n", e.outputPrefix, labelStyle.Render(fmt.Sprintf("Attempt %d:", i+1)))
+		ae.printResult(a.res)
+		if a.err != nil {
+			ae.printValue("Error", a.err.Error())
+		}
+	}
+}
+
+// printExecResult outputs the result of an operation on a host.
+func (e Executor) printExecResult(res execResult) {
+	hostStr := e.outputPrefix + res.h.String()
+	if res.delegateTo != "" {
+		hostStr += " => " + res.delegateTo
+	}
+	fmt.Printf("%s%s  %s\n", hostStyle.Render(hostStr), statusTag(res),
+		timestampStyle.Render(time.Now().Format("[2006-01-02 15:04:05]")))
+	e.printDetails(res)
+}
+
+// statusTag returns the tag shown next to a result that failed, was skipped
+// or changed the host.
+func statusTag(res execResult) string {
+	switch {
+	case res.err != nil:
+		return "  " + failedStyle.Render("failed")
+	case res.skipped:
+		return "  " + skippedStyle.Render("skipped")
+	case res.changed:
+		return "  " + changedStyle.Render("changed")
+	}
+	return ""
+}
+
+// printDetails outputs the name, attempts, fields, error and loop items of
+// the result of an operation on a host.
+func (e Executor) printDetails(res execResult) {
+	if res.name != "" {
+		e.printValue("Name", res.name)
+	}
+	if res.ranOn != "" {
+		e.printValue("Ran On", res.ranOn)
+	}
+	e.printAttempts(res.attempts)
+	e.printResult(res.res)
+	if res.err != nil {
+		e.printValue("Error", res.err.Error())
+	}
+
+	ie := e
+	ie.outputPrefix += "    "
+	for _, it := range res.items {
+		val := it.item
+		switch {
+		case it.err != nil:
+			val += "  " + failedStyle.Render("failed")
+		case it.skipped:
+			val += "  " + skippedStyle.Render("skipped")
+		case it.changed:
+			val += "  " + changedStyle.Render("changed")
+		}
+		e.printValue("Item", val)
+		ie.printAttempts(it.attempts)
+		ie.printResult(it.res)
+		if it.err != nil {
+			ie.printValue("Error", it.err.Error())
+		}
+	}
+}
+
 // execResult holds the result of a remote operation execution.
 type execResult struct {
-	h   remote.Host
-	res *gtypes.OrderedMap[string, string]
+	h          remote.Host
+	delegateTo string
+	name       string // Task name rendered for the host, if it differs from the header
+	ranOn      string // Host a run_once operation ran on, if it is not h
+	changed    bool
+	skipped    bool
+	res        *gtypes.OrderedMap[string, string]
+	items      []itemResult
+	attempts   []attempt
+	err        error
+}
+
+// taskName returns the name of op rendered with the global, play and extra variables,
+// as shown in the task header. References to host variables are left as they are.
+func (e Executor) taskName(pb *Playbook, op operation.Operation) string {
+	ctx := &operation.Context{Vars: pb.Vars.Merge(pb.playVars, e.optExtraVars)}
+	return ctx.Render(op.Name())
 }
 
 // ExecuteOperation runs a single operation on all matching hosts in the group.
 // It respects the operation's Role field: if Role is "all", it runs on all hosts;
-// otherwise, it runs only on hosts in the specified role group.
-func (e Executor) ExecuteOperation(hostGroup map[string][]remote.Host, pool *remote.HostPool, op operation.Operation) error {
+// otherwise, it runs only on hosts in the specified role group. Operations delegated
+// to "local", a group or another host run there once for each matching host,
+// with the matching host's variables. run_once operations run on the first
+// matching host only, and their result is shared with the others.
+// Templates in the operation's fields are expanded with each host's variables,
+// hosts for which the operation's when condition is false are skipped, and
+// looping operations run once per item.
+func (e Executor) ExecuteOperation(pb *Playbook, pool *remote.HostPool, op operation.Operation) error {
+	return e.executeOperation(pb, pool, op, nil)
+}
+
+// executeOperation runs op like ExecuteOperation, on the matching hosts
+// accepted by filter if it is not nil.
+func (e Executor) executeOperation(pb *Playbook, pool *remote.HostPool, op operation.Operation, filter func(remote.Host) bool) error {
+	return e.runOperation(pb, pool, op, filter, e.printExecResult)
+}
+
+// runOperation runs op like executeOperation, passing the result on each
+// host to out, one at a time, instead of printing it.
+func (e Executor) runOperation(pb *Playbook, pool *remote.HostPool, op operation.Operation,
+	filter func(remote.Host) bool, out func(execResult),
+) error {
+	if op.RunOnce() {
+		return e.runOperationOnce(pb, pool, op, filter, out)
+	}
+
 	execResultsChan := make(chan execResult)
+	headerName := e.taskName(pb, op)
 
 	printDone := make(chan struct{})
 	go func() {
 		defer close(printDone)
 		for res := range execResultsChan {
-			hostStr := fmt.Sprintf("%s%s@%s:%d", e.outputPrefix, res.h.User, res.h.Address, res.h.Port)
-			fmt.Printf("%s  %s\n", hostStyle.Render(hostStr),
-				timestampStyle.Render(time.Now().Format("[2006-01-02 15:04:05]")))
-
-			if res.res != nil {
-				_ = res.res.ForEach(func(key, val string) error {
-					e.printValue(key, val)
-					return nil
-				})
-			}
+			out(res)
 		}
 	}()
 
 	sem := semaphore.NewWeighted(int64(e.optMaxProcs))
 	g, ctx := errgroup.WithContext(context.Background())
 
-	for role, hosts := range hostGroup {
+	for role, hosts := range pb.HostGroup {
 		if op.Role() != constants.RoleAll && op.Role() != role {
 			continue
 		}
 
 		for _, h := range hosts {
+			if filter != nil && !filter(h) {
+				continue
+			}
+			if e.failures != nil && e.failures.has(h) {
+				continue
+			}
+
 			if err := sem.Acquire(ctx, 1); err != nil {
 				if ctx.Err() != nil {
 					return ctx.Err()
@@ -129,7 +313,7 @@ func (e Executor) ExecuteOperation(hostGroup map[string][]remote.Host, pool *rem
 				continue
 			}
 
-			r, err := pool.GetRemote(h)
+			t, err := e.transport(pb, pool, op, h)
 			if err != nil {
 				return err
 			}
@@ -138,15 +322,11 @@ func (e Executor) ExecuteOperation(hostGroup map[string][]remote.Host, pool *rem
 			g.Go(func() error {
 				defer sem.Release(1)
 
-				res, err := op.Execute(r)
-				if err != nil {
+				r, err := e.runHostTask(pb, role, currHost, t, op, headerName)
+				if err != nil && e.failures == nil {
 					return err
 				}
-
-				execResultsChan <- execResult{
-					h:   currHost,
-					res: res,
-				}
+				execResultsChan <- r
 				return nil
 			})
 		}
@@ -162,12 +342,72 @@ func (e Executor) ExecuteOperation(hostGroup map[string][]remote.Host, pool *rem
 	return <-errCh
 }
 
-// ExecuteOperations runs a sequence of operations on the host group.
-// Each operation is executed on all hosts that match the operation's Role.
-func (e Executor) ExecuteOperations(hostGroup map[string][]remote.Host, ops []operation.Operation) error {
-	pool := remote.NewHostPool()
-	e.outputPrefix = "    "
+// transport returns the transport op runs through for host h, which is the
+// delegated host's if op is delegated.
+func (e Executor) transport(pb *Playbook, pool *remote.HostPool, op operation.Operation, h remote.Host) (remote.Transport, error) {
+	if op.DelegateTo() == "" {
+		return pool.GetTransport(h)
+	}
+	dh, err := pb.delegateHost(op.DelegateTo())
+	if err != nil {
+		return nil, err
+	}
+	return pool.GetTransport(dh)
+}
+
+// runHostTask runs op on host h in the given role, records its outcome and
+// notifies its handlers if it changed the host. The result is named after the
+// task name rendered for the host if it differs from headerName. A failed host
+// is added to the executor's failed hosts, if failures do not stop the play.
+func (e Executor) runHostTask(pb *Playbook, role string, h remote.Host, t remote.Transport, op operation.Operation, headerName string) (execResult, error) {
+	vars := e.hostVars(pb, role, h, op)
+	ctx := &operation.Context{Vars: vars}
+
+	// Names may refer to variables that only exist per loop iteration, or on
+	// the hosts the when condition selects; those references are shown
+	// unrendered then.
+	name := ctx.Render(op.Name())
+
+	r, err := e.runTask(t, h, op, vars)
+	if name != headerName {
+		r.name = name
+	}
+
+	r.err = err
+	e.record(h, op, r)
+	return r, err
+}
+
+// record records the outcome r of op on host h in the recap. It notifies the
+// handlers of op if r changed the host, and adds the host to the executor's
+// failed hosts if r failed and failures do not stop the play.
+func (e Executor) record(h remote.Host, op operation.Operation, r execResult) {
+	switch {
+	case r.err != nil:
+		e.recap.record(h, statusFailed)
+		if e.failures != nil {
+			e.failures.add(h)
+		}
+	case r.skipped:
+		e.recap.record(h, statusSkipped)
+	case r.changed:
+		e.recap.record(h, statusChanged)
+		e.notified.notify(h, op.Notify()...)
+	default:
+		e.recap.record(h, statusOk)
+	}
+}
 
+// NewHostPool creates a HostPool whose connections honor the executor's options.
+func (e Executor) NewHostPool() *remote.HostPool {
+	if e.optDialer != nil {
+		return remote.NewHostPoolWithDialer(e.optDialer)
+	}
+	return remote.NewHostPool(remote.WithMaxSessions(e.optMaxSessions))
+}
+
+// printHeader outputs the header line of a task.
+func (e Executor) printHeader(name string) {
 	termWidth := 500
 	if term.IsTerminal(int(os.Stdout.Fd())) {
 		if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
@@ -175,23 +415,149 @@ func (e Executor) ExecuteOperations(hostGroup map[string][]remote.Host, ops []op
 		}
 	}
 
-	for _, op := range ops {
-		delim := ""
-		delimLen := termWidth - len(op.Name()) - 2 - 19
-		if delimLen > 0 {
-			delim = strings.Repeat("•", delimLen)
+	delim := ""
+	delimLen := termWidth - len(name) - 2 - 19
+	if delimLen > 0 {
+		delim = strings.Repeat("•", delimLen)
+	}
+	fmt.Printf("%s %s %s\n",
+		taskStyle.Render(name),
+		dimStyle.Render(delim),
+		dimStyle.Render(time.Now().Format("2006-01-02 15:04:05")),
+	)
+}
+
+// ExecuteOperations runs the plays of the playbook in order, each against
+// the hosts it selects. The operations of a play run in sequence, each on all
+// hosts that match the operation's Role, or independently on each host with
+// the free strategy. Operations not selected by the tag filters are left out.
+// Handlers notified by changed tasks run at flush_handlers tasks and at the
+// end of the play. A recap of the task outcomes on each host is printed at
+// the end.
+func (e Executor) ExecuteOperations(pb *Playbook) error {
+	pool := e.NewHostPool()
+	defer func() { _ = pool.Close() }()
+	e.outputPrefix = "    "
+	e.recap = newRecap()
+	defer e.recap.print(e.outputPrefix, e.optCheck)
+
+	for _, play := range pb.Plays {
+		if play.Name != "" {
+			fmt.Println(playStyle.Render("Play: " + play.Name))
+			fmt.Println()
 		}
-		fmt.Printf("%s %s %s\n",
-			taskStyle.Render(op.Name()),
-			dimStyle.Render(delim),
-			dimStyle.Render(time.Now().Format("2006-01-02 15:04:05")),
-		)
 
-		err := e.ExecuteOperation(hostGroup, pool, op)
+		pe := e
+		pe.become = play.Become
+		if play.MaxProcs > 0 {
+			pe.optMaxProcs = play.MaxProcs
+		}
+		if err := pe.executeBatches(pb.forPlay(play), play, pool); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// executeBatches runs a playbook restricted to a play against the batches of
+// hosts of the play's serial setting, one after another.
+func (e Executor) executeBatches(pb *Playbook, play *Play, pool *remote.HostPool) error {
+	hostBatches := batches(pb.hosts(), play.Serial)
+	for i, batch := range hostBatches {
+		if len(hostBatches) > 1 {
+			names := make([]string, len(batch))
+			for j, h := range batch {
+				names[j] = h.String()
+			}
+			fmt.Printf("%s  %s\n\n", playStyle.Render(fmt.Sprintf("Batch %d/%d", i+1, len(hostBatches))),
+				dimStyle.Render(strings.Join(names, ", ")))
+		}
+
+		be := e
+		if play.MaxFailPercentage != nil {
+			be.failures = newHostFailures()
+		}
+		execute := be.executePlay
+		if play.Strategy == StrategyFree {
+			execute = be.executeFree
+		}
+		if err := execute(pb.forBatch(batch), pool); err != nil {
+			return err
+		}
+
+		if be.failures == nil {
+			continue
+		}
+		if failed := be.failures.count(batch); failed*100 > *play.MaxFailPercentage*len(batch) {
+			return fmt.Errorf("%d of %d hosts failed in batch %d/%d, more than max_fail_percentage %d%%",
+				failed, len(batch), i+1, len(hostBatches), *play.MaxFailPercentage)
+		}
+	}
+	return nil
+}
+
+// executePlay runs the operations and notified handlers of a playbook
+// restricted to a play.
+func (e Executor) executePlay(pb *Playbook, pool *remote.HostPool) error {
+	e.notified = newNotifications()
+	if pb.graph != nil && pb.graph.parallel {
+		return e.executeGraph(pb, pool)
+	}
+
+	for _, op := range pb.Operations {
+		if !e.Selected(op) {
+			continue
+		}
+
+		if _, ok := op.(*operation.OpFlushHandlers); ok {
+			if err := e.runHandlers(pb, pool); err != nil {
+				return err
+			}
+			continue
+		}
+
+		e.printHeader(e.taskName(pb, op))
+		err := e.ExecuteOperation(pb, pool, op)
 		if err != nil {
 			return err
 		}
 		fmt.Println()
 	}
-	return nil
+	return e.runHandlers(pb, pool)
+}
+
+// executeGraph runs the operations of a playbook restricted to a play like
+// executePlay, each once the operations it depends on are done on all hosts,
+// with independent operations in parallel. The output of an operation is
+// printed once it is done on all hosts.
+func (e Executor) executeGraph(pb *Playbook, pool *remote.HostPool) error {
+	var mu sync.Mutex
+	err := pb.graph.run(func(i int) error {
+		op := pb.Operations[i]
+		if !e.Selected(op) {
+			return nil
+		}
+		// No other operation runs at the same time as flush_handlers tasks.
+		if _, ok := op.(*operation.OpFlushHandlers); ok {
+			return e.runHandlers(pb, pool)
+		}
+
+		var results []execResult
+		err := e.runOperation(pb, pool, op, nil, func(r execResult) {
+			results = append(results, r)
+		})
+
+		mu.Lock()
+		defer mu.Unlock()
+		e.printHeader(e.taskName(pb, op))
+		for _, r := range results {
+			e.printExecResult(r)
+		}
+		fmt.Println()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return e.runHandlers(pb, pool)
 }
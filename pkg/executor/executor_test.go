@@ -0,0 +1,909 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package executor_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cqroot/minop/pkg/executor"
+	"github.com/cqroot/minop/pkg/remote"
+	"github.com/cqroot/minop/pkg/sshtest"
+	"github.com/stretchr/testify/require"
+)
+
+// commandRecorder is an sshtest.Handler that records every command it
+// receives. reply, if set, handles each command before it is recorded and
+// returns its exit status.
+type commandRecorder struct {
+	mu    sync.Mutex
+	cmds  []string
+	reply func(c *sshtest.Command) int
+}
+
+func (rec *commandRecorder) handle(c *sshtest.Command) int {
+	status := 0
+	if rec.reply != nil {
+		status = rec.reply(c)
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.cmds = append(rec.cmds, c.Cmd)
+	return status
+}
+
+func (rec *commandRecorder) commands() []string {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]string(nil), rec.cmds...)
+}
+
+// writeConfig writes a minop.yaml with the given content into a temporary directory.
+func writeConfig(t *testing.T, content string) string {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "minop.yaml")
+	require.Nil(t, os.WriteFile(filename, []byte(content), 0o644))
+	return filename
+}
+
+// run runs the configuration cfg with a web group of a single test server
+// handled by rec, and returns the commands the server received.
+func (rec *commandRecorder) run(t *testing.T, cfg string, opts ...executor.Option) []string {
+	srv := sshtest.NewServer(t, rec.handle)
+	filename := writeConfig(t, fmt.Sprintf("hosts:\n  web:\n    - %s\n", srv.HostLine())+cfg)
+
+	e := executor.New(opts...)
+	pb, err := e.LoadConfig(filename)
+	require.Nil(t, err)
+	require.Nil(t, e.ExecuteOperations(pb))
+	return rec.commands()
+}
+
+// runConfig runs the configuration cfg with a web group of a single test
+// server, and returns the commands the server received.
+func runConfig(t *testing.T, cfg string, opts ...executor.Option) []string {
+	return (&commandRecorder{}).run(t, cfg, opts...)
+}
+
+func TestExecuteOperations(t *testing.T) {
+	rec := &commandRecorder{}
+	web := sshtest.NewServer(t, rec.handle)
+	db := sshtest.NewServer(t, rec.handle)
+
+	src := filepath.Join(t.TempDir(), "app.conf")
+	require.Nil(t, os.WriteFile(src, []byte("port: 80\n"), 0o644))
+
+	filename := writeConfig(t, fmt.Sprintf(`
+hosts:
+  web:
+    - %s
+  db:
+    - %s
+tasks:
+  - name: Copy config
+    copy: %s
+    to: /etc/app/app.conf
+    role: web
+  - shell: systemctl restart app
+`, web.HostLine(), db.HostLine(), src))
+
+	e := executor.New(executor.WithMaxProcs(2))
+	pb, err := e.LoadConfig(filename)
+	require.Nil(t, err)
+	require.Len(t, pb.Operations, 2)
+
+	require.Nil(t, e.ExecuteOperations(pb))
+
+	content, err := os.ReadFile(filepath.Join(web.Root, "etc", "app", "app.conf"))
+	require.Nil(t, err)
+	require.Equal(t, "port: 80\n", string(content))
+	_, err = os.Stat(filepath.Join(db.Root, "etc", "app", "app.conf"))
+	require.True(t, os.IsNotExist(err))
+
+	require.Equal(t, []string{"systemctl restart app", "systemctl restart app"}, rec.commands())
+}
+
+func TestExecuteOperationsVars(t *testing.T) {
+	web1Rec, web2Rec := &commandRecorder{}, &commandRecorder{}
+	web1 := sshtest.NewServer(t, web1Rec.handle)
+	web2 := sshtest.NewServer(t, web2Rec.handle)
+
+	filename := writeConfig(t, fmt.Sprintf(`
+vars:
+  greeting: hello
+  version: "1.0"
+  env: dev
+hosts:
+  web:
+    vars:
+      greeting: hi
+    hosts:
+      - %s
+      - host: %s
+        vars:
+          node_id: 2
+tasks:
+  - shell: echo {{ .greeting }} {{ index . "node_id" | default 1 }} {{ .version }} {{ .env }} {{ .minop_role }}
+    vars:
+      version: "1.1"
+`, web1.HostLine(), web2.HostLine()))
+
+	e := executor.New(executor.WithExtraVars(map[string]any{"env": "prod"}))
+	pb, err := e.LoadConfig(filename)
+	require.Nil(t, err)
+	require.Nil(t, e.ExecuteOperations(pb))
+
+	require.Equal(t, []string{"echo hi 1 1.1 prod web"}, web1Rec.commands())
+	require.Equal(t, []string{"echo hi 2 1.1 prod web"}, web2Rec.commands())
+}
+
+func TestExecuteOperationsTemplateEscape(t *testing.T) {
+	cmds := runConfig(t, `
+vars:
+  app: web
+tasks:
+  - shell: docker ps --format '{{.Names}}'
+  - shell: docker ps -f name={{ .app }} --format '{{ "{{" }}.Names}}'
+`)
+	require.Equal(t, []string{
+		"docker ps --format '{{.Names}}'",
+		"docker ps -f name=web --format '{{.Names}}'",
+	}, cmds)
+}
+
+func TestExecuteOperationsRegister(t *testing.T) {
+	rec := &commandRecorder{reply: func(c *sshtest.Command) int {
+		if c.Cmd == "cat /etc/version" {
+			_, _ = io.WriteString(c.Stdout, "2.0")
+			return 3
+		}
+		return 0
+	}}
+
+	cmds := rec.run(t, `
+tasks:
+  - shell: cat /etc/version
+    register: version
+  - shell: echo {{ .version.Stdout }} {{ .version.ExitStatus }}
+`)
+	require.Equal(t, []string{"cat /etc/version", "echo 2.0 3"}, cmds)
+}
+
+func TestExecuteOperationsWhen(t *testing.T) {
+	webRec, dbRec := &commandRecorder{}, &commandRecorder{}
+	web := sshtest.NewServer(t, webRec.handle)
+	db := sshtest.NewServer(t, dbRec.handle)
+
+	filename := writeConfig(t, fmt.Sprintf(`
+vars:
+  deploy: true
+hosts:
+  web:
+    - %s
+  db:
+    - %s
+tasks:
+  - shell: hostname
+    register: result
+    when: minop_role == "web"
+  - shell: echo web
+    when: result is defined and result.ExitStatus == 0
+  - shell: echo deploy
+    when: deploy
+`, web.HostLine(), db.HostLine()))
+
+	e := executor.New()
+	pb, err := e.LoadConfig(filename)
+	require.Nil(t, err)
+	require.Nil(t, e.ExecuteOperations(pb))
+
+	require.Equal(t, []string{"hostname", "echo web", "echo deploy"}, webRec.commands())
+	require.Equal(t, []string{"echo deploy"}, dbRec.commands())
+
+	// Hosts skipped by when need not define the variables of the task name.
+	cmds := runConfig(t, `
+tasks:
+  - name: install {{ .pkg }}
+    shell: install {{ .pkg }}
+    when: minop_role == "db"
+  - shell: echo done
+`)
+	require.Equal(t, []string{"echo done"}, cmds)
+}
+
+func TestExecuteOperationsLoop(t *testing.T) {
+	cmds := runConfig(t, `
+vars:
+  packages: [nginx, curl]
+tasks:
+  - name: Create {{ .item }}
+    shell: mkdir -p /srv/{{ .item }}
+    loop: [a, "{{ .minop_role }}"]
+  - shell: install {{ .item }}
+    loop: packages
+    when: item != "curl"
+    register: installed
+  - shell: echo {{ len .installed.Results }} {{ (index .installed.Results 1).Skipped }}
+`)
+	require.Equal(t, []string{
+		"mkdir -p /srv/a",
+		"mkdir -p /srv/web",
+		"install nginx",
+		"echo 2 true",
+	}, cmds)
+
+	// A failed item does not stop the loop, but fails the task afterwards.
+	rec := &commandRecorder{reply: func(c *sshtest.Command) int {
+		if c.Cmd == "install b" {
+			return 1
+		}
+		return 0
+	}}
+	srv := sshtest.NewServer(t, rec.handle)
+	e := executor.New()
+	pb, err := e.LoadConfig(writeConfig(t, fmt.Sprintf(`
+hosts:
+  web:
+    - %s
+tasks:
+  - shell: install {{ .item }}
+    loop: [a, b, c]
+    until: result.ExitStatus == 0
+    retries: 1
+  - shell: echo done
+`, srv.HostLine())))
+	require.Nil(t, err)
+	err = e.ExecuteOperations(pb)
+	require.ErrorContains(t, err, "1 of 3 loop items failed")
+	require.ErrorContains(t, err, `until "result.ExitStatus == 0" is false`)
+	require.Equal(t, []string{"install a", "install b", "install c"}, rec.commands())
+}
+
+func TestExecuteOperationsRetries(t *testing.T) {
+	calls := 0
+	rec := &commandRecorder{reply: func(c *sshtest.Command) int {
+		calls++
+		if calls < 3 {
+			return 7
+		}
+		return 0
+	}}
+
+	cmds := rec.run(t, `
+tasks:
+  - shell: curl localhost/health
+    until: result.ExitStatus == 0
+    retries: 5
+    delay: 1ms
+`, executor.WithVerboseLevel(1))
+	require.Len(t, cmds, 3)
+
+	failing := sshtest.NewServer(t, func(*sshtest.Command) int { return 1 })
+	filename := writeConfig(t, fmt.Sprintf(`
+hosts:
+  all:
+    - %s
+tasks:
+  - shell: "false"
+    register: out
+    until: out.ExitStatus == 0
+    retries: 2
+    delay: 1ms
+`, failing.HostLine()))
+
+	e := executor.New()
+	pb, err := e.LoadConfig(filename)
+	require.Nil(t, err)
+	err = e.ExecuteOperations(pb)
+	require.ErrorContains(t, err, "failed after 2 attempts")
+}
+
+func TestExecuteOperationsHandlers(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "app.conf")
+	require.Nil(t, os.WriteFile(src, []byte("port: 80\n"), 0o644))
+	sum, err := remote.FileChecksum(src)
+	require.Nil(t, err)
+
+	const checksum = "sha256sum -- '/etc/app.conf' 2>/dev/null || shasum -a 256 -- '/etc/app.conf'"
+	rec := &commandRecorder{reply: func(c *sshtest.Command) int {
+		if c.Cmd == checksum {
+			_, _ = io.WriteString(c.Stdout, sum+"  /etc/app.conf\n")
+		}
+		return 0
+	}}
+	cfg := fmt.Sprintf(`
+tasks:
+  - copy: %[1]s
+    to: /etc/app.conf
+    notify: restart app
+  - copy: %[1]s
+    to: /etc/app.conf
+    notify: reload app
+  - shell: echo migrated
+    notify: [restart app]
+  - flush_handlers: true
+  - shell: echo done
+handlers:
+  - name: restart app
+    shell: systemctl restart app
+  - name: reload app
+    shell: systemctl reload app
+`, src)
+	cmds := rec.run(t, cfg)
+	require.Equal(t, []string{checksum, "echo migrated", "systemctl restart app", "echo done"}, cmds)
+
+	// Without a checksum tool on the host, the file is uploaded again.
+	rec = &commandRecorder{reply: func(c *sshtest.Command) int {
+		if c.Cmd == checksum {
+			return 127
+		}
+		return 0
+	}}
+	cmds = rec.run(t, cfg)
+	require.Equal(t, []string{
+		checksum, "echo migrated", "systemctl restart app", "systemctl reload app", "echo done",
+	}, cmds)
+}
+
+func TestSelected(t *testing.T) {
+	filename := writeConfig(t, `
+tasks:
+  - shell: setup
+    tags: setup
+  - shell: deploy
+    tags: [deploy, app]
+  - shell: verify
+    tags: [always]
+  - shell: debug
+    tags: [never, debug]
+  - shell: untagged
+`)
+
+	for _, tc := range []struct {
+		tags, skipTags []string
+		want           []string
+	}{
+		{nil, nil, []string{"setup", "deploy", "verify", "untagged"}},
+		{[]string{"deploy"}, nil, []string{"deploy", "verify"}},
+		{[]string{"all"}, []string{"app"}, []string{"setup", "verify", "untagged"}},
+		{[]string{"debug"}, []string{"always"}, []string{"debug"}},
+		{nil, []string{"setup", "deploy"}, []string{"verify", "untagged"}},
+	} {
+		e := executor.New(executor.WithTags(tc.tags), executor.WithSkipTags(tc.skipTags))
+		pb, err := e.LoadConfig(filename)
+		require.Nil(t, err)
+
+		var selected []string
+		for _, op := range pb.Operations {
+			if e.Selected(op) {
+				selected = append(selected, op.DefaultName()[len("[shell] "):])
+			}
+		}
+		require.Equal(t, tc.want, selected, "tags %v, skip tags %v", tc.tags, tc.skipTags)
+	}
+}
+
+func TestLoadConfigInclude(t *testing.T) {
+	filename := writeConfig(t, `
+vars:
+  app: api
+tasks:
+  - shell: echo start
+  - include: tasks/deploy.yaml
+    role: web
+    vars:
+      version: "1.0"
+`)
+	dir := filepath.Dir(filename)
+	require.Nil(t, os.MkdirAll(filepath.Join(dir, "tasks"), 0o755))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "tasks", "deploy.yaml"), []byte(`
+- shell: deploy {{ .version }}
+- include: verify.yaml
+`), 0o644))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "tasks", "verify.yaml"), []byte(`
+- shell: verify {{ .version }}
+  vars:
+    version: "2.0"
+`), 0o644))
+
+	e := executor.New()
+	pb, err := e.LoadConfig(filename)
+	require.Nil(t, err)
+	require.Len(t, pb.Operations, 3)
+
+	require.Equal(t, filename+":5", pb.Operations[0].Source())
+	require.Equal(t, "all", pb.Operations[0].Role())
+	require.Equal(t, filepath.Join(dir, "tasks", "deploy.yaml")+":2", pb.Operations[1].Source())
+	require.Equal(t, "web", pb.Operations[1].Role())
+	require.Equal(t, "1.0", pb.Operations[1].Vars()["version"])
+	require.Equal(t, filepath.Join(dir, "tasks", "verify.yaml")+":2", pb.Operations[2].Source())
+	require.Equal(t, "web", pb.Operations[2].Role())
+	require.Equal(t, "2.0", pb.Operations[2].Vars()["version"])
+
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "tasks", "verify.yaml"), []byte(`
+- include: ../minop.yaml
+`), 0o644))
+	_, err = e.LoadConfig(filename)
+	require.ErrorContains(t, err, "include cycle")
+}
+
+func TestExecuteOperationsUseRole(t *testing.T) {
+	rec := &commandRecorder{}
+	srv := sshtest.NewServer(t, rec.handle)
+
+	filename := writeConfig(t, fmt.Sprintf(`
+vars:
+  user: nginx
+hosts:
+  web:
+    - %s
+tasks:
+  - use_role: nginx
+    role: web
+    vars:
+      port: 8080
+`, srv.HostLine()))
+
+	roleDir := filepath.Join(filepath.Dir(filename), "roles", "nginx")
+	require.Nil(t, os.MkdirAll(filepath.Join(roleDir, "files"), 0o755))
+	for name, content := range map[string]string{
+		"defaults.yaml":   "port: 80\nuser: www\n",
+		"files/site.conf": "listen 80;\n",
+		"tasks.yaml": `
+- copy: site.conf
+  to: /etc/nginx/site.conf
+  notify: restart nginx
+- shell: echo {{ .port }} {{ .user }}
+`,
+		"handlers.yaml": `
+- name: restart nginx
+  shell: systemctl restart nginx
+`,
+	} {
+		require.Nil(t, os.WriteFile(filepath.Join(roleDir, name), []byte(content), 0o644))
+	}
+
+	e := executor.New()
+	pb, err := e.LoadConfig(filename)
+	require.Nil(t, err)
+	require.Nil(t, e.ExecuteOperations(pb))
+
+	content, err := os.ReadFile(filepath.Join(srv.Root, "etc", "nginx", "site.conf"))
+	require.Nil(t, err)
+	require.Equal(t, "listen 80;\n", string(content))
+	require.Equal(t, []string{"echo 8080 nginx", "systemctl restart nginx"}, rec.commands())
+}
+
+func TestExecuteOperationsPlays(t *testing.T) {
+	rec := &commandRecorder{}
+	lb := sshtest.NewServer(t, rec.handle)
+	app := sshtest.NewServer(t, rec.handle)
+
+	filename := writeConfig(t, fmt.Sprintf(`
+vars:
+  version: "1.0"
+hosts:
+  lb:
+    - %s
+  app:
+    vars:
+      version: "0.9"
+    hosts:
+      - %s
+plays:
+  - name: Drain
+    hosts: lb
+    tasks:
+      - shell: disable app
+  - name: Deploy
+    hosts: [app]
+    max_procs: 2
+    vars:
+      version: "2.0"
+    tasks:
+      - shell: deploy {{ .version }}
+  - hosts: lb
+    tasks:
+      - shell: enable app
+`, lb.HostLine(), app.HostLine()))
+
+	e := executor.New()
+	pb, err := e.LoadConfig(filename)
+	require.Nil(t, err)
+	require.Len(t, pb.Plays, 3)
+	require.Len(t, pb.Operations, 3)
+	require.Nil(t, e.ExecuteOperations(pb))
+
+	require.Equal(t, []string{"disable app", "deploy 2.0", "enable app"}, rec.commands())
+}
+
+func TestExecuteOperationsBecome(t *testing.T) {
+	cmds := runConfig(t, `
+plays:
+  - hosts: web
+    become: true
+    tasks:
+      - shell: systemctl restart app
+        env:
+          APP_ENV: prod
+  - hosts: web
+    tasks:
+      - shell: whoami
+`)
+	require.Equal(t, []string{
+		"sudo -n -- sh -c 'export APP_ENV='\\''prod'\\''\nsystemctl restart app'",
+		"whoami",
+	}, cmds)
+}
+
+func TestExecuteOperationsSerial(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		log []string
+	)
+	newServer := func(name string, status int) *sshtest.Server {
+		return sshtest.NewServer(t, func(c *sshtest.Command) int {
+			mu.Lock()
+			defer mu.Unlock()
+			log = append(log, name+": "+c.Cmd)
+			return status
+		})
+	}
+	a, b, c := newServer("a", 0), newServer("b", 0), newServer("c", 0)
+
+	filename := writeConfig(t, fmt.Sprintf(`
+serial: [1, "50%%"]
+hosts:
+  web:
+    - %s
+    - %s
+    - %s
+tasks:
+  - shell: stop
+  - shell: start
+`, a.HostLine(), b.HostLine(), c.HostLine()))
+
+	e := executor.New(executor.WithMaxProcs(3))
+	pb, err := e.LoadConfig(filename)
+	require.Nil(t, err)
+	require.Nil(t, e.ExecuteOperations(pb))
+	require.Equal(t, []string{
+		"a: stop", "a: start",
+		"b: stop", "b: start",
+		"c: stop", "c: start",
+	}, log)
+
+	bad := newServer("bad", 1)
+	config := `
+max_fail_percentage: %d
+hosts:
+  web:
+    - %s
+    - %s
+tasks:
+  - shell: check
+    until: result.ExitStatus == 0
+    retries: 1
+  - shell: deploy
+`
+	for _, tc := range []struct {
+		maxFail int
+		ok      bool
+	}{
+		{50, true},
+		{0, false},
+	} {
+		log = nil
+		pb, err := e.LoadConfig(writeConfig(t, fmt.Sprintf(config, tc.maxFail, bad.HostLine(), a.HostLine())))
+		require.Nil(t, err)
+
+		err = e.ExecuteOperations(pb)
+		require.Equal(t, tc.ok, err == nil, err)
+		require.NotContains(t, log, "bad: deploy")
+		require.Contains(t, log, "a: deploy")
+	}
+}
+
+func TestExecuteOperationsFree(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		log []string
+	)
+	fastDone := make(chan struct{})
+	slow := sshtest.NewServer(t, func(c *sshtest.Command) int {
+		if c.Cmd == "first" {
+			// The slow host only goes on once the fast one finished all its
+			// tasks, which it can only do with the free strategy.
+			select {
+			case <-fastDone:
+			case <-time.After(5 * time.Second):
+				return 1
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		log = append(log, "slow: "+c.Cmd)
+		return 0
+	})
+	fast := sshtest.NewServer(t, func(c *sshtest.Command) int {
+		mu.Lock()
+		defer mu.Unlock()
+		log = append(log, "fast: "+c.Cmd)
+		if c.Cmd == "second" {
+			close(fastDone)
+		}
+		return 0
+	})
+
+	filename := writeConfig(t, fmt.Sprintf(`
+strategy: free
+hosts:
+  web:
+    - %s
+    - %s
+tasks:
+  - shell: first
+  - shell: second
+`, slow.HostLine(), fast.HostLine()))
+
+	e := executor.New(executor.WithMaxProcs(2))
+	pb, err := e.LoadConfig(filename)
+	require.Nil(t, err)
+	require.Nil(t, e.ExecuteOperations(pb))
+	require.Equal(t, []string{
+		"fast: first", "fast: second",
+		"slow: first", "slow: second",
+	}, log)
+}
+
+func TestExecuteOperationsGraph(t *testing.T) {
+	cfg := `
+strategy: %s
+tasks:
+  - id: prep
+    shell: prep
+  - shell: a
+    depends_on: prep
+  - id: b
+    shell: b
+    depends_on: [prep]
+  - shell: done
+`
+	for _, strategy := range []string{executor.StrategyLinear, executor.StrategyFree} {
+		bStarted := make(chan struct{})
+		rec := &commandRecorder{reply: func(c *sshtest.Command) int {
+			switch c.Cmd {
+			case "a":
+				// a only finishes once b started, which needs them to run in parallel.
+				select {
+				case <-bStarted:
+				case <-time.After(5 * time.Second):
+					return 1
+				}
+			case "b":
+				close(bStarted)
+			}
+			return 0
+		}}
+		cmds := rec.run(t, fmt.Sprintf(cfg, strategy))
+		require.Equal(t, []string{"prep", "b", "a", "done"}, cmds, strategy)
+	}
+
+	pb, err := executor.New().LoadConfig(writeConfig(t, fmt.Sprintf(cfg, executor.StrategyLinear)))
+	require.Nil(t, err)
+	require.Equal(t, [][]int{nil, {0}, {0}, {1, 2}}, pb.Plays[0].Graph.Deps)
+
+	var dot strings.Builder
+	require.Nil(t, pb.WriteDot(&dot))
+	require.Contains(t, dot.String(), `"p1_prep" -> "p1_b";`)
+	require.Contains(t, dot.String(), `"p1_#2" -> "p1_#4";`)
+}
+
+func TestExecuteOperationsRunOnceDelegate(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		log []string
+	)
+	newServer := func(name string) *sshtest.Server {
+		return sshtest.NewServer(t, func(c *sshtest.Command) int {
+			mu.Lock()
+			defer mu.Unlock()
+			log = append(log, name+": "+c.Cmd)
+			return 0
+		})
+	}
+	app1, app2, lb := newServer("app1"), newServer("app2"), newServer("lb")
+
+	filename := writeConfig(t, fmt.Sprintf(`
+hosts:
+  app:
+    - %s
+    - %s
+  lb:
+    - %s
+tasks:
+  - shell: migrate
+    role: app
+    run_once: true
+    register: migration
+  - shell: drain {{ .minop_port }}
+    role: app
+    delegate_to: lb
+  - shell: echo {{ .migration.ExitStatus }}
+    role: app
+`, app1.HostLine(), app2.HostLine(), lb.HostLine()))
+
+	for _, strategy := range []string{executor.StrategyLinear, executor.StrategyFree} {
+		log = nil
+		e := executor.New(executor.WithMaxProcs(2))
+		pb, err := e.LoadConfig(filename)
+		require.Nil(t, err)
+		pb.Plays[0].Strategy = strategy
+		require.Nil(t, e.ExecuteOperations(pb), strategy)
+
+		require.Subset(t, log, []string{
+			fmt.Sprintf("lb: drain %d", app1.Host().Port), fmt.Sprintf("lb: drain %d", app2.Host().Port),
+			"app1: echo 0", "app2: echo 0",
+		}, strategy)
+		require.Len(t, log, 5, strategy)
+		if strategy == executor.StrategyLinear {
+			require.Equal(t, "app1: migrate", log[0])
+		}
+	}
+}
+
+func TestExecuteOperationsCheck(t *testing.T) {
+	cmds := runConfig(t, `
+tasks:
+  - shell: systemctl restart app
+  - shell: cat /etc/app.conf
+    check_mode: false
+  - shell: useradd app
+    check: id app
+    register: user
+  - shell: echo {{ .user.Changed }}
+    check_mode: false
+`, executor.WithCheck(true))
+	require.Equal(t, []string{"cat /etc/app.conf", "id app", "echo false"}, cmds)
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"invalid register name", `
+tasks:
+  - shell: "true"
+    register: my-result
+`, "invalid register name"},
+		{"invalid when", `
+tasks:
+  - shell: "true"
+    when: minop_role ==
+`, ""},
+		{"invalid loop", `
+tasks:
+  - shell: "true"
+    loop: {a: 1}
+`, "loop must be a list"},
+		{"undefined handler", `
+tasks:
+  - shell: "true"
+    notify: missing
+`, `handler "missing" is not defined`},
+		{"missing role", `
+tasks:
+  - use_role: missing
+`, "not found"},
+		{"become with copy", `
+plays:
+  - hosts: all
+    become: true
+    tasks:
+      - shell: systemctl stop app
+      - copy: app.conf
+        to: /etc/app.conf
+`, `task "[copy] app.conf => /etc/app.conf": become does not apply to copy and template tasks`},
+		{"become with template handler", `
+plays:
+  - hosts: all
+    become: true
+    tasks:
+      - shell: "true"
+    handlers:
+      - name: render config
+        template: app.conf.tmpl
+        to: /etc/app.conf
+`, `task "render config": become does not apply`},
+		{"unknown play hosts", `
+plays:
+  - hosts: missing
+`, `unknown role "missing"`},
+		{"tasks with plays", `
+tasks:
+  - shell: "true"
+plays:
+  - hosts: all
+`, "must be defined in the plays"},
+		{"unknown strategy", `
+strategy: random
+tasks:
+  - shell: "true"
+`, `unknown strategy "random"`},
+		{"dependency cycle", `
+tasks:
+  - id: a
+    shell: a
+    depends_on: b
+  - id: b
+    shell: b
+    depends_on: a
+`, "dependency cycle: a -> b -> a"},
+		{"unknown dependency", `
+tasks:
+  - shell: a
+    depends_on: missing
+`, `unknown id "missing"`},
+		{"dependency across flush_handlers", `
+tasks:
+  - id: a
+    shell: a
+  - flush_handlers: true
+  - shell: b
+    depends_on: a
+`, "other side of a flush_handlers task"},
+		{"unknown delegate", `
+tasks:
+  - shell: drain
+    delegate_to: missing
+`, `unknown host "missing"`},
+	} {
+		_, err := executor.New().LoadConfig(writeConfig(t, tc.content))
+		require.NotNil(t, err, tc.name)
+		require.ErrorContains(t, err, tc.err, tc.name)
+	}
+}
+
+func TestParseExtraVars(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "vars.yaml")
+	require.Nil(t, os.WriteFile(file, []byte("a: 1\nb: [x, y]\n"), 0o644))
+
+	vars, err := executor.ParseExtraVars([]string{"a=0", "@" + file, "c=x=y"})
+	require.Nil(t, err)
+	require.Equal(t, map[string]any{"a": 1, "b": []any{"x", "y"}, "c": "x=y"}, map[string]any(vars))
+
+	vars, err = executor.ParseExtraVars([]string{
+		"debug=false", "port=8080", "version='1.0'", "name=app", "empty=", "list=[x",
+	})
+	require.Nil(t, err)
+	require.Equal(t, map[string]any{
+		"debug": false, "port": 8080, "version": "1.0", "name": "app", "empty": "", "list": "[x",
+	}, map[string]any(vars))
+
+	_, err = executor.ParseExtraVars([]string{"novalue"})
+	require.NotNil(t, err)
+}
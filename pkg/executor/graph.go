@@ -0,0 +1,276 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cqroot/minop/pkg/operation"
+)
+
+// taskIDRegexp matches valid task ids.
+var taskIDRegexp = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+
+// Graph is the dependency graph of the tasks of a play. A task runs once the
+// tasks it depends on are done. A task without depends_on depends on all tasks
+// before it, so tasks run in order unless depends_on says otherwise.
+// flush_handlers tasks run once all tasks before them are done, and before
+// any task after them.
+type Graph struct {
+	// Operations are the tasks of the play, in order.
+	Operations []operation.Operation
+	// Deps holds the indexes of the tasks each task directly depends on.
+	Deps [][]int
+
+	parallel bool // Whether any task sets depends_on
+}
+
+// newGraph builds the dependency graph of ops, checking that the tasks only
+// depend on tasks between the same flush_handlers tasks and that there are
+// no dependency cycles.
+func newGraph(ops []operation.Operation) (*Graph, error) {
+	g := &Graph{
+		Operations: ops,
+		Deps:       make([][]int, len(ops)),
+	}
+
+	ids := make(map[string]int)
+	for i, op := range ops {
+		if op.ID() == "" {
+			continue
+		}
+		if _, ok := ids[op.ID()]; ok {
+			return nil, fmt.Errorf("task %q: duplicate id %q", op.Name(), op.ID())
+		}
+		ids[op.ID()] = i
+	}
+
+	// Each task after a flush_handlers task depends on it, directly or
+	// through the tasks it depends on.
+	barrier, start := -1, 0
+	for i, op := range ops {
+		_, flush := op.(*operation.OpFlushHandlers)
+		switch {
+		case flush && op.DependsOn() != nil:
+			return nil, fmt.Errorf("task %q: depends_on cannot be set on flush_handlers tasks", op.Name())
+		case op.DependsOn() == nil:
+			g.Deps[i] = g.frontier(start, i)
+		default:
+			g.parallel = true
+			for _, id := range op.DependsOn() {
+				j, ok := ids[id]
+				if !ok {
+					return nil, fmt.Errorf("task %q: depends_on: unknown id %q", op.Name(), id)
+				}
+				if j < start || j >= g.nextBarrier(i) {
+					return nil, fmt.Errorf("task %q: depends_on: task %q is on the other side of a flush_handlers task",
+						op.Name(), id)
+				}
+				g.Deps[i] = append(g.Deps[i], j)
+			}
+		}
+		if len(g.Deps[i]) == 0 && barrier >= 0 {
+			g.Deps[i] = []int{barrier}
+		}
+
+		if flush {
+			barrier, start = i, i+1
+		}
+	}
+
+	if cycle := g.cycle(); cycle != nil {
+		keys := make([]string, len(cycle))
+		for k, i := range cycle {
+			keys[k] = g.Key(i)
+		}
+		return nil, errors.New("dependency cycle: " + strings.Join(keys, " -> "))
+	}
+	return g, nil
+}
+
+// frontier returns the tasks from start to end, exclusive, that none of
+// these tasks depends on.
+func (g *Graph) frontier(start, end int) []int {
+	needed := make(map[int]bool)
+	for i := start; i < end; i++ {
+		for _, j := range g.Deps[i] {
+			needed[j] = true
+		}
+	}
+
+	var tasks []int
+	for i := start; i < end; i++ {
+		if !needed[i] {
+			tasks = append(tasks, i)
+		}
+	}
+	return tasks
+}
+
+// nextBarrier returns the index of the first flush_handlers task after task
+// i, or the number of tasks if there is none.
+func (g *Graph) nextBarrier(i int) int {
+	for j := i + 1; j < len(g.Operations); j++ {
+		if _, ok := g.Operations[j].(*operation.OpFlushHandlers); ok {
+			return j
+		}
+	}
+	return len(g.Operations)
+}
+
+// cycle returns the tasks of a dependency cycle, starting and ending with the
+// same task, or nil if there is none.
+func (g *Graph) cycle() []int {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(g.Operations))
+	var path []int
+
+	var visit func(i int) []int
+	visit = func(i int) []int {
+		state[i] = visiting
+		path = append(path, i)
+		for _, j := range g.Deps[i] {
+			switch state[j] {
+			case visiting:
+				for k, p := range path {
+					if p == j {
+						return append(append([]int(nil), path[k:]...), j)
+					}
+				}
+			case unvisited:
+				if cycle := visit(j); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = visited
+		return nil
+	}
+
+	for i := range g.Operations {
+		if state[i] == unvisited {
+			if cycle := visit(i); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// Key returns the id of task i, or its position "#<n>" if it has none.
+func (g *Graph) Key(i int) string {
+	if id := g.Operations[i].ID(); id != "" {
+		return id
+	}
+	return "#" + strconv.Itoa(i+1)
+}
+
+// run calls fn for each task once fn returned for the tasks it depends on,
+// for independent tasks in parallel. After an error, it starts no more tasks
+// and returns the first error once the running tasks are done.
+func (g *Graph) run(fn func(i int) error) error {
+	type result struct {
+		i   int
+		err error
+	}
+
+	remaining := make([]int, len(g.Operations))
+	dependents := make([][]int, len(g.Operations))
+	for i, deps := range g.Deps {
+		remaining[i] = len(deps)
+		for _, j := range deps {
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+
+	done := make(chan result)
+	running := 0
+	start := func(i int) {
+		running++
+		go func() { done <- result{i, fn(i)} }()
+	}
+	for i := range g.Operations {
+		if remaining[i] == 0 {
+			start(i)
+		}
+	}
+
+	var firstErr error
+	for running > 0 {
+		r := <-done
+		running--
+		if r.err != nil && firstErr == nil {
+			firstErr = r.err
+		}
+		if firstErr != nil {
+			continue
+		}
+		for _, i := range dependents[r.i] {
+			remaining[i]--
+			if remaining[i] == 0 {
+				start(i)
+			}
+		}
+	}
+	return firstErr
+}
+
+// WriteDot writes the dependency graphs of the plays in the DOT language,
+// with an edge from each task to the tasks depending on it. The tasks of each
+// play are grouped in a cluster.
+func (pb *Playbook) WriteDot(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("digraph minop {\n")
+	sb.WriteString("  node [shape=box];\n")
+	for p, play := range pb.Plays {
+		node := func(i int) string {
+			return strconv.Quote(fmt.Sprintf("p%d_%s", p+1, play.Graph.Key(i)))
+		}
+
+		label := play.Name
+		if label == "" {
+			label = "hosts: " + strings.Join(play.Hosts, ", ")
+		}
+		fmt.Fprintf(&sb, "  subgraph cluster_%d {\n", p+1)
+		fmt.Fprintf(&sb, "    label=%s;\n", strconv.Quote(label))
+		for i, op := range play.Graph.Operations {
+			fmt.Fprintf(&sb, "    %s [label=%s];\n", node(i),
+				strconv.Quote(play.Graph.Key(i)+"\n"+op.DefaultName()))
+		}
+		for i, deps := range play.Graph.Deps {
+			for _, j := range deps {
+				fmt.Fprintf(&sb, "    %s -> %s;\n", node(j), node(i))
+			}
+		}
+		sb.WriteString("  }\n")
+	}
+	sb.WriteString("}\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
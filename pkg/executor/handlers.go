@@ -0,0 +1,121 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package executor
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/cqroot/minop/pkg/operation"
+	"github.com/cqroot/minop/pkg/remote"
+)
+
+// notifications tracks the hosts each handler was notified for. It is safe
+// for concurrent use.
+type notifications struct {
+	mu    sync.Mutex
+	hosts map[string]map[remote.Host]bool
+}
+
+// newNotifications creates an empty set of notifications.
+func newNotifications() *notifications {
+	return &notifications{
+		hosts: make(map[string]map[remote.Host]bool),
+	}
+}
+
+// notify records that the handlers were notified for the host.
+func (n *notifications) notify(h remote.Host, handlers ...string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, handler := range handlers {
+		if n.hosts[handler] == nil {
+			n.hosts[handler] = make(map[remote.Host]bool)
+		}
+		n.hosts[handler][h] = true
+	}
+}
+
+// take returns and clears the hosts the handler was notified for.
+func (n *notifications) take(handler string) map[remote.Host]bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	hosts := n.hosts[handler]
+	delete(n.hosts, handler)
+	return hosts
+}
+
+// takeHost reports whether the handler was notified for the host, and clears
+// the notification.
+func (n *notifications) takeHost(handler string, h remote.Host) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if !n.hosts[handler][h] {
+		return false
+	}
+	delete(n.hosts[handler], h)
+	return true
+}
+
+// runHandlers runs the notified handlers in the order they are defined, each
+// once on the hosts it was notified for. Handlers can notify later handlers.
+func (e Executor) runHandlers(pb *Playbook, pool *remote.HostPool) error {
+	for _, handler := range pb.Handlers {
+		hosts := e.notified.take(handler.Name())
+		if len(hosts) == 0 {
+			continue
+		}
+
+		e.printHeader(e.taskName(pb, handler))
+		err := e.executeOperation(pb, pool, handler, func(h remote.Host) bool {
+			// A host in several groups runs the handler only once.
+			if !hosts[h] {
+				return false
+			}
+			delete(hosts, h)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// validateNotify checks that the operations only notify defined handlers.
+func validateNotify(ops []operation.Operation, handlers []operation.Operation) error {
+	names := make(map[string]bool, len(handlers))
+	for _, handler := range handlers {
+		names[handler.Name()] = true
+	}
+
+	for _, ops := range [][]operation.Operation{ops, handlers} {
+		for _, op := range ops {
+			for _, name := range op.Notify() {
+				if !names[name] {
+					return fmt.Errorf("task %q: notify: handler %q is not defined", op.Name(), name)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/cqroot/minop/pkg/operation"
+	"gopkg.in/yaml.v3"
+)
+
+// RolesDir is the directory, next to the configuration file, that holds the
+// roles applied with use_role.
+const RolesDir = "roles"
+
+// taskConfig is a task entry. It is either a task, an include of a task file
+// under "include", or a role applied with "use_role". The tasks of includes
+// and roles get the entry's vars and role.
+type taskConfig struct {
+	operation.Input `yaml:",inline"`
+	Include         string `yaml:"include"`
+	UseRole         string `yaml:"use_role"`
+
+	line int
+}
+
+// UnmarshalYAML decodes the entry and records its line.
+func (tc *taskConfig) UnmarshalYAML(value *yaml.Node) error {
+	type plain taskConfig
+	if err := value.Decode((*plain)(tc)); err != nil {
+		return err
+	}
+	tc.line = value.Line
+	return nil
+}
+
+// taskLoader creates the operations of task entries, expanding includes and roles.
+type taskLoader struct {
+	rolesDir string
+	// handlers holds the handlers of the roles used, each role's once.
+	handlers []operation.Operation
+	roles    map[string]bool
+}
+
+// newTaskLoader creates a taskLoader for the configuration file.
+func newTaskLoader(filename string) *taskLoader {
+	return &taskLoader{
+		rolesDir: filepath.Join(filepath.Dir(filename), RolesDir),
+		roles:    make(map[string]bool),
+	}
+}
+
+// load creates the operations of the task entries of file, expanding includes
+// and roles in place. Included files are resolved relative to the including
+// file and hold a list of task entries themselves. stack holds the files
+// being loaded, to detect include cycles. Relative copy, script and template
+// sources of tasks in roleDir resolve against its files and templates.
+func (l *taskLoader) load(entries []taskConfig, file, roleDir string, stack []string) ([]operation.Operation, error) {
+	var ops []operation.Operation
+	for _, tc := range entries {
+		source := fmt.Sprintf("%s:%d", file, tc.line)
+
+		var (
+			expanded []operation.Operation
+			err      error
+		)
+		switch {
+		case tc.Include != "" && tc.UseRole != "":
+			err = errors.New("include and use_role are mutually exclusive")
+		case tc.Include != "":
+			expanded, err = l.include(tc, file, roleDir, stack)
+		case tc.UseRole != "":
+			expanded, err = l.useRole(tc, stack)
+		default:
+			var op operation.Operation
+			op, err = newOperation(roleInput(tc.Input, roleDir))
+			if err == nil {
+				op.SetSource(source)
+				expanded = []operation.Operation{op}
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+		ops = append(ops, expanded...)
+	}
+	return ops, nil
+}
+
+// loadFile loads the task entries of a task file.
+func (l *taskLoader) loadFile(path, roleDir string, stack []string) ([]operation.Operation, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(stack, abs) {
+		return nil, fmt.Errorf("include cycle: %s -> %s", strings.Join(stack, " -> "), abs)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []taskConfig
+	if err := yaml.Unmarshal(content, &entries); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return l.load(entries, path, roleDir, append(slices.Clone(stack), abs))
+}
+
+// include loads the tasks of the file included by tc.
+func (l *taskLoader) include(tc taskConfig, file, roleDir string, stack []string) ([]operation.Operation, error) {
+	if err := checkExpandEntry(tc); err != nil {
+		return nil, fmt.Errorf("include %s: %w", tc.Include, err)
+	}
+
+	path := tc.Include
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(file), path)
+	}
+
+	ops, err := l.loadFile(path, roleDir, stack)
+	if err != nil {
+		return nil, fmt.Errorf("include: %w", err)
+	}
+	applyEntry(tc, ops)
+	return ops, nil
+}
+
+// useRole loads the tasks of the role applied by tc from roles/<name>:
+// tasks.yaml holds its tasks and the optional handlers.yaml its handlers.
+// The variables of the optional defaults.yaml have the lowest precedence.
+func (l *taskLoader) useRole(tc taskConfig, stack []string) ([]operation.Operation, error) {
+	name := tc.UseRole
+	if err := checkExpandEntry(tc); err != nil {
+		return nil, fmt.Errorf("use_role %s: %w", name, err)
+	}
+
+	dir := filepath.Join(l.rolesDir, name)
+	if info, err := os.Stat(dir); strings.ContainsAny(name, `/\`) || err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("use_role: role %q not found in %s", name, l.rolesDir)
+	}
+
+	var defaults operation.Vars
+	content, err := os.ReadFile(filepath.Join(dir, "defaults.yaml"))
+	if err == nil {
+		err = yaml.Unmarshal(content, &defaults)
+	} else if errors.Is(err, os.ErrNotExist) {
+		err = nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("use_role %s: defaults: %w", name, err)
+	}
+
+	ops, err := l.loadFile(filepath.Join(dir, "tasks.yaml"), dir, stack)
+	if err != nil {
+		return nil, fmt.Errorf("use_role %s: %w", name, err)
+	}
+	for _, op := range ops {
+		op.SetDefaults(defaults.Merge(op.Defaults()))
+	}
+	applyEntry(tc, ops)
+
+	if l.roles[name] {
+		return ops, nil
+	}
+	l.roles[name] = true
+
+	handlersFile := filepath.Join(dir, "handlers.yaml")
+	if _, err := os.Stat(handlersFile); errors.Is(err, os.ErrNotExist) {
+		return ops, nil
+	}
+	handlers, err := l.loadFile(handlersFile, dir, stack)
+	if err != nil {
+		return nil, fmt.Errorf("use_role %s: handlers: %w", name, err)
+	}
+	for _, op := range handlers {
+		op.SetDefaults(defaults.Merge(op.Defaults()))
+	}
+	l.handlers = append(l.handlers, handlers...)
+	return ops, nil
+}
+
+// checkExpandEntry checks that an include or use_role entry sets no task
+// fields besides vars and role.
+func checkExpandEntry(tc taskConfig) error {
+	rest := tc.Input
+	rest.Vars, rest.Role = nil, ""
+	if !reflect.DeepEqual(rest, operation.Input{}) {
+		return errors.New("only vars and role can be set")
+	}
+	return nil
+}
+
+// applyEntry applies the vars, which the tasks' own vars take precedence
+// over, and the role of an include or use_role entry to its tasks.
+func applyEntry(tc taskConfig, ops []operation.Operation) {
+	for _, op := range ops {
+		if tc.Vars != nil {
+			op.SetVars(tc.Vars.Merge(op.Vars()))
+		}
+		if tc.Role != "" {
+			op.SetRole(tc.Role)
+		}
+	}
+}
+
+// roleInput resolves the relative local sources of a task in roleDir: copy,
+// script and stdin_file sources against its files directory, and template
+// sources against its templates directory.
+func roleInput(in operation.Input, roleDir string) operation.Input {
+	if roleDir == "" {
+		return in
+	}
+
+	resolve := func(p *string, sub string) {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(roleDir, sub, *p)
+		}
+	}
+	resolve(&in.Copy, "files")
+	resolve(&in.Script, "files")
+	resolve(&in.StdinFile, "files")
+	resolve(&in.Template, "templates")
+	return in
+}
@@ -18,10 +18,14 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 package executor
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/cqroot/minop/pkg/constants"
+	"github.com/cqroot/minop/pkg/expr"
 	"github.com/cqroot/minop/pkg/logs"
 	"github.com/cqroot/minop/pkg/operation"
 	"github.com/cqroot/minop/pkg/remote"
@@ -30,60 +34,244 @@ import (
 
 // config represents the structure of the minop configuration file.
 type config struct {
-	// Hosts maps role names to lists of host connection strings.
-	// Each host string has the format "<user>:<password>@<address>:<port>".
-	Hosts map[string][]string `yaml:"hosts"`
+	// Vars defines the global variables.
+	Vars operation.Vars `yaml:"vars"`
+	// Hosts maps role names to groups of hosts.
+	Hosts map[string]hostGroupConfig `yaml:"hosts"`
 	// Tasks defines the list of operations to execute.
-	Tasks []operation.Input `yaml:"tasks"`
+	Tasks []taskConfig `yaml:"tasks"`
+	// Handlers defines the operations run at the end when notified by a changed task.
+	Handlers []taskConfig `yaml:"handlers"`
+	// Strategy is how the hosts go through the Tasks, "linear" or "free".
+	Strategy string `yaml:"strategy"`
+	// Serial and MaxFailPercentage set up rolling updates of the Tasks.
+	Serial            serialConfig `yaml:"serial"`
+	MaxFailPercentage *int         `yaml:"max_fail_percentage"`
+	// Plays defines lists of tasks run in order against different host groups,
+	// instead of Tasks and Handlers.
+	Plays []playConfig `yaml:"plays"`
 }
 
-// LoadConfig reads and parses the configuration file, returning the host groups
-// and operation list. The filename can be an absolute or relative path.
-func (e Executor) LoadConfig(filename string) (map[string][]remote.Host, []operation.Operation, error) {
+// hostGroupConfig is a group in the hosts section. It is either a list of
+// hosts, or a mapping with the list under "hosts" and group variables under "vars".
+type hostGroupConfig struct {
+	Hosts []hostConfig   `yaml:"hosts"`
+	Vars  operation.Vars `yaml:"vars"`
+}
+
+// UnmarshalYAML accepts both forms of a host group.
+func (g *hostGroupConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.SequenceNode {
+		return value.Decode(&g.Hosts)
+	}
+
+	type plain hostGroupConfig
+	return value.Decode((*plain)(g))
+}
+
+// hostConfig is a host entry. It is either a host string in the format
+// "<user>:<password>@<address>:<port>", or a mapping with the host string
+// under "host" and host variables under "vars".
+type hostConfig struct {
+	Host string         `yaml:"host"`
+	Vars operation.Vars `yaml:"vars"`
+}
+
+// UnmarshalYAML accepts both forms of a host entry.
+func (h *hostConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&h.Host)
+	}
+
+	type plain hostConfig
+	return value.Decode((*plain)(h))
+}
+
+// Playbook is the loaded content of a configuration file.
+type Playbook struct {
+	// HostGroup maps role names to their hosts.
+	HostGroup map[string][]remote.Host
+	// Plays are the plays to run, in order. A configuration file without
+	// plays has a single play of its tasks against all hosts.
+	Plays []*Play
+	// Operations are the tasks of all plays, in order.
+	Operations []operation.Operation
+	// Handlers are the handlers of all plays, in order.
+	Handlers []operation.Operation
+	// Vars holds the global variables.
+	Vars operation.Vars
+	// GroupVars maps role names to the variables of their hosts.
+	GroupVars map[string]operation.Vars
+	// HostVars holds the variables of individual hosts.
+	HostVars map[remote.Host]operation.Vars
+
+	// inventory maps role names to their hosts in the whole configuration
+	// file, which tasks can be delegated to.
+	inventory map[string][]remote.Host
+	// playVars holds the variables of the play a playbook is restricted to.
+	playVars operation.Vars
+	// graph is the dependency graph of the play a playbook is restricted to.
+	graph *Graph
+}
+
+// LoadConfig reads and parses the configuration file, returning the host groups,
+// variables and operation list. The filename can be an absolute or relative path.
+func (e Executor) LoadConfig(filename string) (*Playbook, error) {
 	content, err := os.ReadFile(filename)
 	if err != nil {
 		logs.Logger().Error().Err(err).Msg("failed to read file")
-		return nil, nil, err
+		return nil, err
 	}
 
 	var cfg config
 	err = yaml.Unmarshal(content, &cfg)
 	if err != nil {
 		logs.Logger().Error().Err(err).Msg("failed to unmarshal YAML data")
-		return nil, nil, fmt.Errorf("failed to unmarshal YAML data: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal YAML data: %w", err)
 	}
 
-	hostGroup := make(map[string][]remote.Host)
-	for role, lines := range cfg.Hosts {
-		for _, line := range lines {
-			h, err := remote.ParseHostLine(line)
+	pb := Playbook{
+		HostGroup: make(map[string][]remote.Host),
+		Vars:      cfg.Vars,
+		GroupVars: make(map[string]operation.Vars),
+		HostVars:  make(map[remote.Host]operation.Vars),
+	}
+	for role, group := range cfg.Hosts {
+		if group.Vars != nil {
+			pb.GroupVars[role] = group.Vars
+		}
+
+		for _, hc := range group.Hosts {
+			h, err := remote.ParseHostLine(hc.Host)
 			if err != nil {
-				return nil, nil, fmt.Errorf("parse host line for role %q: %w", role, err)
+				return nil, fmt.Errorf("parse host line for role %q: %w", role, err)
+			}
+			pb.HostGroup[role] = append(pb.HostGroup[role], h)
+
+			if hc.Vars != nil {
+				pb.HostVars[h] = pb.HostVars[h].Merge(hc.Vars)
 			}
-			hostGroup[role] = append(hostGroup[role], h)
 		}
 	}
 
-	ops := make([]operation.Operation, len(cfg.Tasks))
-	for idx, in := range cfg.Tasks {
-		op, err := operation.GetOperation(in)
-		if err != nil {
-			return nil, nil, err
+	pb.inventory = pb.HostGroup
+
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	hasPlays := len(cfg.Plays) > 0
+	if !hasPlays {
+		cfg.Plays = []playConfig{{
+			Hosts:             []string{constants.RoleAll},
+			Tasks:             cfg.Tasks,
+			Handlers:          cfg.Handlers,
+			Strategy:          cfg.Strategy,
+			Serial:            cfg.Serial,
+			MaxFailPercentage: cfg.MaxFailPercentage,
+		}}
+	} else if len(cfg.Tasks) > 0 || len(cfg.Handlers) > 0 || len(cfg.Serial) > 0 ||
+		cfg.MaxFailPercentage != nil || cfg.Strategy != "" {
+		return nil, errors.New("tasks, handlers, strategy, serial and max_fail_percentage must be defined in the plays")
+	}
+
+	for idx, pc := range cfg.Plays {
+		play, err := loadPlay(pc, &pb, filename, abs)
+		if err != nil && hasPlays {
+			return nil, fmt.Errorf("play %d: %w", idx+1, err)
+		} else if err != nil {
+			return nil, err
 		}
+		pb.Plays = append(pb.Plays, play)
+		pb.Operations = append(pb.Operations, play.Operations...)
+		pb.Handlers = append(pb.Handlers, play.Handlers...)
+	}
+	return &pb, nil
+}
+
+// newOperation creates the operation of a task and applies the task settings
+// shared by all operation types.
+func newOperation(in operation.Input) (operation.Operation, error) {
+	op, err := operation.GetOperation(in)
+	if err != nil {
+		return nil, err
+	}
 
-		if in.Name != "" {
-			op.SetName(in.Name)
-		} else {
-			op.SetName(op.DefaultName())
+	if in.Name != "" {
+		op.SetName(in.Name)
+	} else {
+		op.SetName(op.DefaultName())
+	}
+
+	if in.Role != "" {
+		op.SetRole(in.Role)
+	} else {
+		op.SetRole(constants.RoleAll)
+	}
+
+	op.SetDelegateTo(in.DelegateTo)
+	op.SetRunOnce(in.RunOnce)
+	op.SetVars(in.Vars)
+
+	if in.Register != "" && !varNameRegexp.MatchString(in.Register) {
+		return nil, fmt.Errorf("task %q: invalid register name %q", op.Name(), in.Register)
+	}
+	op.SetRegister(in.Register)
+
+	if in.When != "" {
+		if _, err := expr.Parse(in.When); err != nil {
+			return nil, fmt.Errorf("task %q: %w", op.Name(), err)
 		}
+	}
+	op.SetWhen(in.When)
+
+	if err := validateLoop(in.Loop); err != nil {
+		return nil, fmt.Errorf("task %q: %w", op.Name(), err)
+	}
+	op.SetLoop(in.Loop)
+
+	retry, err := newRetry(in)
+	if err != nil {
+		return nil, fmt.Errorf("task %q: %w", op.Name(), err)
+	}
+	op.SetRetry(retry)
+	op.SetNotify(in.Notify)
+	op.SetTags(in.Tags)
 
-		if in.Role != "" {
-			op.SetRole(in.Role)
-		} else {
-			op.SetRole(constants.RoleAll)
+	if in.ID != "" && !taskIDRegexp.MatchString(in.ID) {
+		return nil, fmt.Errorf("task %q: invalid id %q", op.Name(), in.ID)
+	}
+	op.SetID(in.ID)
+	op.SetDependsOn(in.DependsOn)
+
+	return op, nil
+}
+
+// newRetry validates the retries, delay and until settings of a task.
+func newRetry(in operation.Input) (operation.Retry, error) {
+	retry := operation.Retry{
+		Retries: in.Retries,
+		Until:   in.Until,
+	}
+	if in.Retries < 0 {
+		return retry, fmt.Errorf("invalid retries %d", in.Retries)
+	}
+	if in.Until != "" {
+		if _, err := expr.Parse(in.Until); err != nil {
+			return retry, err
 		}
+		if retry.Retries == 0 {
+			retry.Retries = DefaultRetries
+		}
+	}
 
-		ops[idx] = op
+	retry.Delay = DefaultRetryDelay
+	if in.Delay != "" {
+		delay, err := time.ParseDuration(in.Delay)
+		if err != nil || delay < 0 {
+			return retry, fmt.Errorf("invalid delay %q", in.Delay)
+		}
+		retry.Delay = delay
 	}
-	return hostGroup, ops, nil
+	return retry, nil
 }
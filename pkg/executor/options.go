@@ -17,6 +17,11 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 
 package executor
 
+import (
+	"github.com/cqroot/minop/pkg/operation"
+	"github.com/cqroot/minop/pkg/remote"
+)
+
 // Option configures an Executor.
 type Option func(e *Executor)
 
@@ -27,6 +32,32 @@ func WithVerboseLevel(verboseLevel int) Option {
 	}
 }
 
+// WithMaxSessions sets the maximum number of concurrent SSH sessions per host.
+// A value of 0 or negative is ignored and remote.DefaultMaxSessions is used.
+func WithMaxSessions(maxSessions int) Option {
+	return func(e *Executor) {
+		if maxSessions > 0 {
+			e.optMaxSessions = maxSessions
+		}
+	}
+}
+
+// WithDialer sets the function used to connect to hosts, replacing SSH.
+// It allows operations to run over other transports such as remote.Fake.
+func WithDialer(dialer remote.Dialer) Option {
+	return func(e *Executor) {
+		e.optDialer = dialer
+	}
+}
+
+// WithExtraVars sets variables that take precedence over all variables
+// defined in the configuration file.
+func WithExtraVars(vars operation.Vars) Option {
+	return func(e *Executor) {
+		e.optExtraVars = vars
+	}
+}
+
 // WithMaxProcs sets the maximum number of concurrent operations.
 // A value of 0 or negative is ignored and the default (1) is used.
 func WithMaxProcs(maxProcs int) Option {
@@ -36,3 +67,34 @@ func WithMaxProcs(maxProcs int) Option {
 		}
 	}
 }
+
+// WithTags selects the tasks to run by tag. Tasks without any of the tags are
+// skipped unless they are tagged always.
+func WithTags(tags []string) Option {
+	return func(e *Executor) {
+		e.optTags = tags
+	}
+}
+
+// WithSkipTags skips the tasks that have any of the tags.
+func WithSkipTags(tags []string) Option {
+	return func(e *Executor) {
+		e.optSkipTags = tags
+	}
+}
+
+// WithCheck enables check mode: operations predict whether they would change
+// the hosts instead of changing them, and those that cannot are skipped.
+func WithCheck(check bool) Option {
+	return func(e *Executor) {
+		e.optCheck = check
+	}
+}
+
+// WithDiff enables diff mode: operations that upload files show how the
+// remote files change.
+func WithDiff(diff bool) Option {
+	return func(e *Executor) {
+		e.optDiff = diff
+	}
+}
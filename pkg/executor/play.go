@@ -0,0 +1,178 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/cqroot/minop/pkg/constants"
+	"github.com/cqroot/minop/pkg/operation"
+	"github.com/cqroot/minop/pkg/remote"
+)
+
+// playConfig is an entry of the plays list of the configuration file.
+type playConfig struct {
+	Name     string               `yaml:"name"`
+	Hosts    operation.StringList `yaml:"hosts"`
+	Vars     operation.Vars       `yaml:"vars"`
+	MaxProcs int                  `yaml:"max_procs"`
+	Become   bool                 `yaml:"become"`
+	Strategy string               `yaml:"strategy"`
+
+	Serial            serialConfig `yaml:"serial"`
+	MaxFailPercentage *int         `yaml:"max_fail_percentage"`
+
+	Tasks    []taskConfig `yaml:"tasks"`
+	Handlers []taskConfig `yaml:"handlers"`
+}
+
+// Play is a list of tasks run against a selection of host groups.
+type Play struct {
+	// Name is shown before the tasks of the play, if set.
+	Name string
+	// Hosts are the roles the play targets, constants.RoleAll for every host.
+	Hosts []string
+	// Vars holds the play variables, which take precedence over host variables.
+	Vars operation.Vars
+	// MaxProcs overrides the executor's maximum number of concurrent
+	// operations if positive.
+	MaxProcs int
+	// Become runs the commands of the play as root through sudo.
+	Become bool
+	// Strategy is how the hosts go through the tasks, StrategyLinear or
+	// StrategyFree.
+	Strategy string
+	// Serial holds the sizes of the batches of hosts the play runs against
+	// one after another, the last size repeating. Without sizes, the play
+	// runs against all its hosts at once.
+	Serial []BatchSize
+	// MaxFailPercentage, if set, lets the play go on when hosts fail: failed
+	// hosts skip the remaining tasks, and the play stops after a batch only if
+	// more than this percentage of its hosts failed. Otherwise the play stops
+	// at the first failure.
+	MaxFailPercentage *int
+	// Operations are the tasks of the play, in order.
+	Operations []operation.Operation
+	// Graph is the dependency graph of the Operations.
+	Graph *Graph
+	// Handlers are the operations notified by changed tasks of the play, in order.
+	Handlers []operation.Operation
+}
+
+// loadPlay creates the play of an entry of the plays list of filename.
+func loadPlay(pc playConfig, pb *Playbook, filename, abs string) (*Play, error) {
+	if pc.MaxProcs < 0 {
+		return nil, fmt.Errorf("invalid max_procs %d", pc.MaxProcs)
+	}
+	switch pc.Strategy {
+	case "":
+		pc.Strategy = StrategyLinear
+	case StrategyLinear, StrategyFree:
+	default:
+		return nil, fmt.Errorf("unknown strategy %q", pc.Strategy)
+	}
+	if len(pc.Hosts) == 0 {
+		return nil, errors.New("hosts must be set")
+	}
+	for _, role := range pc.Hosts {
+		if _, ok := pb.HostGroup[role]; !ok && role != constants.RoleAll {
+			return nil, fmt.Errorf("hosts: unknown role %q", role)
+		}
+	}
+
+	if pc.MaxFailPercentage != nil && (*pc.MaxFailPercentage < 0 || *pc.MaxFailPercentage > 100) {
+		return nil, fmt.Errorf("invalid max_fail_percentage %d", *pc.MaxFailPercentage)
+	}
+	serial, err := parseSerial(pc.Serial)
+	if err != nil {
+		return nil, err
+	}
+
+	play := &Play{
+		Name:              pc.Name,
+		Hosts:             pc.Hosts,
+		Vars:              pc.Vars,
+		MaxProcs:          pc.MaxProcs,
+		Become:            pc.Become,
+		Strategy:          pc.Strategy,
+		Serial:            serial,
+		MaxFailPercentage: pc.MaxFailPercentage,
+	}
+
+	loader := newTaskLoader(filename)
+	play.Operations, err = loader.load(pc.Tasks, filename, "", []string{abs})
+	if err != nil {
+		return nil, err
+	}
+	play.Handlers, err = loader.load(pc.Handlers, filename, "", []string{abs})
+	if err != nil {
+		return nil, err
+	}
+	play.Handlers = append(play.Handlers, loader.handlers...)
+
+	if err := validateNotify(play.Operations, play.Handlers); err != nil {
+		return nil, err
+	}
+	if err := pb.validateDelegates(slices.Concat(play.Operations, play.Handlers)); err != nil {
+		return nil, err
+	}
+	if play.Become {
+		if err := validateBecome(slices.Concat(play.Operations, play.Handlers)); err != nil {
+			return nil, err
+		}
+	}
+	play.Graph, err = newGraph(play.Operations)
+	if err != nil {
+		return nil, err
+	}
+	return play, nil
+}
+
+// forPlay returns a copy of the playbook restricted to the hosts, variables,
+// tasks and handlers of the play.
+func (pb *Playbook) forPlay(play *Play) *Playbook {
+	ppb := *pb
+	ppb.Operations = play.Operations
+	ppb.Handlers = play.Handlers
+	ppb.Plays = []*Play{play}
+	ppb.playVars = play.Vars
+	ppb.graph = play.Graph
+
+	if !slices.Contains(play.Hosts, constants.RoleAll) {
+		ppb.HostGroup = make(map[string][]remote.Host, len(play.Hosts))
+		for _, role := range play.Hosts {
+			ppb.HostGroup[role] = pb.HostGroup[role]
+		}
+	}
+	return &ppb
+}
+
+// validateBecome checks that the operations of a become play only run
+// commands. Copy and template tasks upload their files over SFTP as the login
+// user, so they cannot write where only root can.
+func validateBecome(ops []operation.Operation) error {
+	for _, op := range ops {
+		switch op.(type) {
+		case *operation.OpCopy, *operation.OpTemplate:
+			return fmt.Errorf("task %q: become does not apply to copy and template tasks", op.Name())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package executor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/cqroot/minop/pkg/remote"
+)
+
+var (
+	changedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+	skippedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("14"))
+	failedStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+)
+
+// taskStatus is the outcome of a task on a host.
+type taskStatus int
+
+const (
+	statusOk taskStatus = iota
+	statusChanged
+	statusSkipped
+	statusFailed
+)
+
+// hostRecap counts the task outcomes of a host. Changed tasks count as ok too.
+type hostRecap struct {
+	ok, changed, skipped, failed int
+}
+
+// recap collects the task outcomes of every host. It is safe for concurrent use.
+type recap struct {
+	mu    sync.Mutex
+	hosts map[remote.Host]*hostRecap
+}
+
+// newRecap creates an empty recap.
+func newRecap() *recap {
+	return &recap{
+		hosts: make(map[remote.Host]*hostRecap),
+	}
+}
+
+// record counts a task outcome for the host.
+func (r *recap) record(h remote.Host, status taskStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	hr := r.hosts[h]
+	if hr == nil {
+		hr = &hostRecap{}
+		r.hosts[h] = hr
+	}
+	switch status {
+	case statusOk:
+		hr.ok++
+	case statusChanged:
+		hr.ok++
+		hr.changed++
+	case statusSkipped:
+		hr.skipped++
+	case statusFailed:
+		hr.failed++
+	}
+}
+
+// print outputs the outcome counts of every host, sorted by host. In check
+// mode, the changes are predicted ones.
+func (r *recap) print(prefix string, check bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.hosts) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(r.hosts))
+	counts := make(map[string]*hostRecap, len(r.hosts))
+	width := 0
+	for h, hr := range r.hosts {
+		name := h.String()
+		names = append(names, name)
+		counts[name] = hr
+		width = max(width, len(name))
+	}
+	sort.Strings(names)
+
+	if check {
+		fmt.Println(taskStyle.Render("Recap") + "  " + dimStyle.Render("check mode, changes are predicted"))
+	} else {
+		fmt.Println(taskStyle.Render("Recap"))
+	}
+	for _, name := range names {
+		hr := counts[name]
+		failed := fmt.Sprintf("failed=%d", hr.failed)
+		if hr.failed > 0 {
+			failed = failedStyle.Render(failed)
+		}
+		fmt.Printf("%s%s%s  ok=%d  %s  %s  %s\n", prefix, hostStyle.Render(name),
+			strings.Repeat(" ", width-len(name)), hr.ok,
+			changedStyle.Render(fmt.Sprintf("changed=%d", hr.changed)),
+			skippedStyle.Render(fmt.Sprintf("skipped=%d", hr.skipped)), failed)
+	}
+}
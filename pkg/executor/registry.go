@@ -0,0 +1,88 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package executor
+
+import (
+	"sync"
+
+	"github.com/cqroot/gtypes"
+	"github.com/cqroot/minop/pkg/operation"
+	"github.com/cqroot/minop/pkg/remote"
+)
+
+// registry holds the task results registered as variables, per host.
+// It is safe for concurrent use.
+type registry struct {
+	mu   sync.Mutex
+	vars map[remote.Host]operation.Vars
+}
+
+// newRegistry creates an empty registry.
+func newRegistry() *registry {
+	return &registry{
+		vars: make(map[remote.Host]operation.Vars),
+	}
+}
+
+// resultVars returns the fields of an operation result as a variable value.
+// The Changed field is a boolean.
+func resultVars(res *gtypes.OrderedMap[string, string]) map[string]any {
+	val := make(map[string]any)
+	if res != nil {
+		_ = res.ForEach(func(key, v string) error {
+			val[key] = v
+			return nil
+		})
+		if _, ok := val[operation.ResultChanged]; ok {
+			val[operation.ResultChanged] = operation.Changed(res)
+		}
+	}
+	return val
+}
+
+// register stores the value under name for the host.
+func (r *registry) register(h remote.Host, name string, val map[string]any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.vars[h] == nil {
+		r.vars[h] = make(operation.Vars)
+	}
+	r.vars[h][name] = val
+}
+
+// get returns a copy of the variables registered for the host.
+func (r *registry) get(h remote.Host) operation.Vars {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.vars[h].Merge()
+}
+
+// share registers the value registered under name for host from for host to
+// as well, if there is one.
+func (r *registry) share(from, to remote.Host, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	val, ok := r.vars[from][name]
+	if !ok {
+		return
+	}
+	if r.vars[to] == nil {
+		r.vars[to] = make(operation.Vars)
+	}
+	r.vars[to][name] = val
+}
@@ -0,0 +1,142 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package executor
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/cqroot/minop/pkg/constants"
+	"github.com/cqroot/minop/pkg/operation"
+	"github.com/cqroot/minop/pkg/remote"
+)
+
+// runOperationOnce runs the run_once operation op like runOperation, but only
+// on the first matching host, in the order of the sorted roles. The other
+// matching hosts share its result.
+func (e Executor) runOperationOnce(pb *Playbook, pool *remote.HostPool, op operation.Operation,
+	filter func(remote.Host) bool, out func(execResult),
+) error {
+	roles := make([]string, 0, len(pb.HostGroup))
+	for role := range pb.HostGroup {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+
+	var (
+		first execResult
+		ran   bool
+	)
+	seen := make(map[remote.Host]bool)
+	for _, role := range roles {
+		if op.Role() != constants.RoleAll && op.Role() != role {
+			continue
+		}
+
+		for _, h := range pb.HostGroup[role] {
+			if seen[h] || (filter != nil && !filter(h)) {
+				continue
+			}
+			if e.failures != nil && e.failures.has(h) {
+				continue
+			}
+			seen[h] = true
+
+			if ran {
+				out(e.shareResult(h, op, first))
+				continue
+			}
+
+			t, err := e.transport(pb, pool, op, h)
+			if err != nil {
+				return err
+			}
+			first, err = e.runHostTask(pb, role, h, t, op, e.taskName(pb, op))
+			if err != nil && e.failures == nil {
+				return err
+			}
+			out(first)
+			ran = true
+		}
+	}
+	return nil
+}
+
+// shareResult applies the result r of a run_once operation on another host to
+// host h: h gets the same registered variable, recap status and handler
+// notifications, and fails if the operation failed.
+func (e Executor) shareResult(h remote.Host, op operation.Operation, r execResult) execResult {
+	s := execResult{
+		h:          h,
+		delegateTo: r.delegateTo,
+		ranOn:      r.h.String(),
+		changed:    r.changed,
+		skipped:    r.skipped,
+	}
+	if r.err != nil {
+		s.err = fmt.Errorf("task %q failed on %s", op.Name(), r.h)
+	} else if op.Register() != "" {
+		e.registered.share(r.h, h, op.Register())
+	}
+	e.record(h, op, s)
+	return s
+}
+
+// onceResults holds the results of the run_once operations of a play, for
+// hosts that reach them independently with the free strategy. It is safe
+// for concurrent use.
+type onceResults struct {
+	mu      sync.Mutex
+	results map[operation.Operation]*onceResult
+}
+
+// onceResult is the result of a run_once operation, available once done is closed.
+type onceResult struct {
+	done chan struct{}
+	r    execResult
+	err  error
+}
+
+// newOnceResults creates an empty set of run_once results.
+func newOnceResults() *onceResults {
+	return &onceResults{
+		results: make(map[operation.Operation]*onceResult),
+	}
+}
+
+// do calls run for the first host to reach op and returns its result. The
+// other hosts wait for that result and get it with ran set to false.
+func (o *onceResults) do(op operation.Operation, run func() (execResult, error)) (r execResult, ran bool, err error) {
+	o.mu.Lock()
+	res, ok := o.results[op]
+	if !ok {
+		res = &onceResult{done: make(chan struct{})}
+		o.results[op] = res
+	}
+	o.mu.Unlock()
+
+	if ok {
+		<-res.done
+		return res.r, false, res.err
+	}
+
+	defer close(res.done)
+	res.r, res.err = run()
+	return res.r, true, res.err
+}
@@ -0,0 +1,162 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package executor
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cqroot/minop/pkg/remote"
+	"gopkg.in/yaml.v3"
+)
+
+// BatchSize is the number of hosts, or the percentage of the play's hosts,
+// in a batch of a rolling update.
+type BatchSize struct {
+	Value   int
+	Percent bool
+}
+
+// serialConfig is the serial setting of a play: a batch size, or a list of
+// batch sizes of which the last one repeats. Batch sizes are host counts or
+// percentages such as "25%".
+type serialConfig []string
+
+// UnmarshalYAML accepts a single batch size or a list of them.
+func (s *serialConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*s = serialConfig{value.Value}
+		return nil
+	}
+	return value.Decode((*[]string)(s))
+}
+
+// parseSerial parses the batch sizes of a serial setting.
+func parseSerial(s serialConfig) ([]BatchSize, error) {
+	sizes := make([]BatchSize, 0, len(s))
+	for _, item := range s {
+		str, percent := strings.CutSuffix(strings.TrimSpace(item), "%")
+		n, err := strconv.Atoi(strings.TrimSpace(str))
+		if err != nil || n <= 0 || (percent && n > 100) {
+			return nil, fmt.Errorf("invalid serial batch size %q", item)
+		}
+		sizes = append(sizes, BatchSize{Value: n, Percent: percent})
+	}
+	return sizes, nil
+}
+
+// batches splits the hosts into batches of the given sizes, repeating the
+// last size. Percentages are rounded down, to at least one host.
+func batches(hosts []remote.Host, sizes []BatchSize) [][]remote.Host {
+	if len(sizes) == 0 {
+		return [][]remote.Host{hosts}
+	}
+
+	total := len(hosts)
+	var ret [][]remote.Host
+	for i := 0; len(hosts) > 0; i++ {
+		size := sizes[min(i, len(sizes)-1)]
+		n := size.Value
+		if size.Percent {
+			n = max(size.Value*total/100, 1)
+		}
+		n = min(n, len(hosts))
+		ret = append(ret, hosts[:n])
+		hosts = hosts[n:]
+	}
+	return ret
+}
+
+// hosts returns the distinct hosts of the playbook, ordered by role name.
+func (pb *Playbook) hosts() []remote.Host {
+	roles := make([]string, 0, len(pb.HostGroup))
+	for role := range pb.HostGroup {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+
+	var hosts []remote.Host
+	for _, role := range roles {
+		for _, h := range pb.HostGroup[role] {
+			if !slices.Contains(hosts, h) {
+				hosts = append(hosts, h)
+			}
+		}
+	}
+	return hosts
+}
+
+// forBatch returns a copy of the playbook restricted to the hosts of a batch.
+func (pb *Playbook) forBatch(batch []remote.Host) *Playbook {
+	bpb := *pb
+	bpb.HostGroup = make(map[string][]remote.Host, len(pb.HostGroup))
+	for role, hosts := range pb.HostGroup {
+		for _, h := range hosts {
+			if slices.Contains(batch, h) {
+				bpb.HostGroup[role] = append(bpb.HostGroup[role], h)
+			}
+		}
+	}
+	return &bpb
+}
+
+// hostFailures records the hosts that failed a task. They skip the remaining
+// tasks of the play. It is safe for concurrent use.
+type hostFailures struct {
+	mu    sync.Mutex
+	hosts map[remote.Host]bool
+}
+
+// newHostFailures creates an empty set of failed hosts.
+func newHostFailures() *hostFailures {
+	return &hostFailures{
+		hosts: make(map[remote.Host]bool),
+	}
+}
+
+// add records that the host failed.
+func (f *hostFailures) add(h remote.Host) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hosts[h] = true
+}
+
+// has reports whether the host failed.
+func (f *hostFailures) has(h remote.Host) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.hosts[h]
+}
+
+// count returns how many of the hosts failed.
+func (f *hostFailures) count(hosts []remote.Host) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n := 0
+	for _, h := range hosts {
+		if f.hosts[h] {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,222 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cqroot/minop/pkg/constants"
+	"github.com/cqroot/minop/pkg/operation"
+	"github.com/cqroot/minop/pkg/remote"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+)
+
+// Play strategies.
+const (
+	// StrategyLinear runs each task on all hosts before the next task starts.
+	StrategyLinear = "linear"
+	// StrategyFree lets each host run all tasks without waiting for the others.
+	StrategyFree = "free"
+)
+
+// hostRun holds the results of the tasks run on a host with the free strategy.
+type hostRun struct {
+	h     remote.Host
+	tasks []execResult
+}
+
+// executeFree runs the operations and notified handlers of a playbook
+// restricted to a play with the free strategy: each host runs through all
+// operations on its own, up to optMaxProcs hosts at a time. The output of a
+// host is printed once it is done.
+func (e Executor) executeFree(pb *Playbook, pool *remote.HostPool) error {
+	e.notified = newNotifications()
+	e.onceRuns = newOnceResults()
+	roles := hostRoles(pb)
+
+	runs := make(chan hostRun)
+	printDone := make(chan struct{})
+	go func() {
+		defer close(printDone)
+		for run := range runs {
+			e.printHostRun(run)
+		}
+	}()
+
+	sem := semaphore.NewWeighted(int64(e.optMaxProcs))
+	g, ctx := errgroup.WithContext(context.Background())
+	for _, h := range pb.hosts() {
+		if err := sem.Acquire(ctx, 1); err != nil {
+			break
+		}
+
+		currHost := h
+		g.Go(func() error {
+			defer sem.Release(1)
+
+			run, err := e.runHost(ctx, pb, pool, currHost, roles[currHost])
+			runs <- run
+			return err
+		})
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- g.Wait()
+		close(runs)
+	}()
+
+	<-printDone
+	return <-errCh
+}
+
+// runHost runs the operations of a playbook on host h, which belongs to the
+// given roles, and the handlers notified for it at flush_handlers tasks and at
+// the end. A run_once operation runs on the first host to reach it, and the
+// others share its result. Each operation runs once the operations it depends
+// on are done on the host, with independent operations in parallel. It stops
+// at the first failure, or when ctx is canceled because another host failed.
+func (e Executor) runHost(ctx context.Context, pb *Playbook, pool *remote.HostPool, h remote.Host, roles []string) (hostRun, error) {
+	run := hostRun{h: h}
+	var mu sync.Mutex
+
+	// runOp runs op on the host once for each of its roles the operation
+	// matches, or only for the first of them if once is set.
+	runOp := func(op operation.Operation, once bool) error {
+		once = once || op.RunOnce()
+		for _, role := range roles {
+			if op.Role() != constants.RoleAll && op.Role() != role {
+				continue
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			t, err := e.transport(pb, pool, op, h)
+			if err != nil {
+				return err
+			}
+
+			var r execResult
+			if op.RunOnce() {
+				var ran bool
+				r, ran, err = e.onceRuns.do(op, func() (execResult, error) {
+					return e.runHostTask(pb, role, h, t, op, "")
+				})
+				if !ran {
+					name := r.name
+					r = e.shareResult(h, op, r)
+					r.name, err = name, r.err
+				}
+			} else {
+				r, err = e.runHostTask(pb, role, h, t, op, "")
+			}
+			mu.Lock()
+			run.tasks = append(run.tasks, r)
+			mu.Unlock()
+			if err != nil || once {
+				return err
+			}
+		}
+		return nil
+	}
+
+	runHandlers := func() error {
+		for _, handler := range pb.Handlers {
+			if !e.notified.takeHost(handler.Name(), h) {
+				continue
+			}
+			if err := runOp(handler, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Operations the host runs at the same time are independent of each
+	// other, so their order in the output does not matter.
+	err := pb.graph.run(func(i int) error {
+		op := pb.Operations[i]
+		if !e.Selected(op) {
+			return nil
+		}
+		if _, ok := op.(*operation.OpFlushHandlers); ok {
+			return runHandlers()
+		}
+		return runOp(op, false)
+	})
+	if err != nil {
+		return run, e.hostError(err)
+	}
+	return run, e.hostError(runHandlers())
+}
+
+// hostError returns the error that stopped a host, or nil if failures do
+// not stop the play.
+func (e Executor) hostError(err error) error {
+	if e.failures != nil {
+		return nil
+	}
+	return err
+}
+
+// hostRoles returns the sorted roles of each host of the playbook.
+func hostRoles(pb *Playbook) map[remote.Host][]string {
+	roles := make(map[remote.Host][]string)
+	for role, hosts := range pb.HostGroup {
+		for _, h := range hosts {
+			if !slices.Contains(roles[h], role) {
+				roles[h] = append(roles[h], role)
+			}
+		}
+	}
+	for _, r := range roles {
+		sort.Strings(r)
+	}
+	return roles
+}
+
+// printHostRun outputs the results of the tasks run on a host, each under
+// the task name rendered for the host. Hosts no task ran on are left out.
+func (e Executor) printHostRun(run hostRun) {
+	if len(run.tasks) == 0 {
+		return
+	}
+	fmt.Printf("%s  %s\n", hostStyle.Render(e.outputPrefix+run.h.String()),
+		timestampStyle.Render(time.Now().Format("[2006-01-02 15:04:05]")))
+
+	te := e
+	te.outputPrefix += "    "
+	for _, res := range run.tasks {
+		name := res.name
+		if res.delegateTo != "" {
+			name += " => " + res.delegateTo
+		}
+		fmt.Printf("%s%s%s\n", te.outputPrefix, taskStyle.Render(name), statusTag(res))
+
+		res.name = ""
+		te.printDetails(res)
+	}
+	fmt.Println()
+}
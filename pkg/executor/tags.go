@@ -0,0 +1,62 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package executor
+
+import (
+	"slices"
+
+	"github.com/cqroot/minop/pkg/operation"
+)
+
+// Special task tags.
+const (
+	// TagAlways marks tasks that run unless their tags are skipped explicitly.
+	TagAlways = "always"
+	// TagNever marks tasks that only run when their tags are selected explicitly.
+	TagNever = "never"
+	// TagAll selects every task that is not tagged never.
+	TagAll = "all"
+)
+
+// Selected reports whether op runs with the executor's tag filters. A task is
+// skipped if it has any of the skipped tags. Otherwise, if tags are selected,
+// it runs only if it has one of them or is tagged always. flush_handlers tasks
+// are always selected.
+func (e Executor) Selected(op operation.Operation) bool {
+	if _, ok := op.(*operation.OpFlushHandlers); ok {
+		return true
+	}
+
+	tags := op.Tags()
+	hasAny := func(filter []string) bool {
+		return slices.ContainsFunc(tags, func(tag string) bool {
+			return slices.Contains(filter, tag)
+		})
+	}
+
+	if hasAny(e.optSkipTags) {
+		return false
+	}
+	if slices.Contains(tags, TagNever) {
+		return hasAny(e.optTags)
+	}
+	if len(e.optTags) == 0 || slices.Contains(e.optTags, TagAll) {
+		return true
+	}
+	return slices.Contains(tags, TagAlways) || hasAny(e.optTags)
+}
@@ -0,0 +1,247 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/cqroot/gtypes"
+	"github.com/cqroot/minop/pkg/expr"
+	"github.com/cqroot/minop/pkg/operation"
+	"github.com/cqroot/minop/pkg/remote"
+)
+
+// Retry defaults for tasks that set until without retries or delay.
+const (
+	DefaultRetries    = 3
+	DefaultRetryDelay = 5 * time.Second
+)
+
+// itemResult holds the result of one iteration of a looping operation.
+type itemResult struct {
+	item     string
+	changed  bool
+	skipped  bool
+	res      *gtypes.OrderedMap[string, string]
+	attempts []attempt
+	err      error
+}
+
+// attempt holds the outcome of an unsuccessful attempt of a retried operation.
+type attempt struct {
+	res *gtypes.OrderedMap[string, string]
+	err error
+}
+
+// runTask runs op on host h through t with the host's variables and registers
+// its result. Looping operations run once per item, with the item exposed as
+// {{ .item }} and its position as {{ .loop_index }}.
+func (e Executor) runTask(t remote.Transport, h remote.Host, op operation.Operation, vars operation.Vars) (execResult, error) {
+	r := execResult{h: h, delegateTo: op.DelegateTo()}
+
+	if op.Loop() == nil {
+		res, skipped, attempts, err := e.runOnce(t, h, op, &operation.Context{Vars: vars})
+		r.attempts = attempts
+		if err != nil {
+			return r, err
+		}
+		r.res, r.skipped, r.changed = res, skipped, operation.Changed(res)
+		if op.Register() != "" && !skipped {
+			e.registered.register(h, op.Register(), resultVars(res))
+		}
+		return r, nil
+	}
+
+	items, err := loopItems(op.Loop(), &operation.Context{Vars: vars})
+	if err != nil {
+		return r, fmt.Errorf("task %q on %s: loop: %w", op.Name(), h, err)
+	}
+
+	r.skipped = true
+	results := make([]any, 0, len(items))
+	var firstErr error
+	failed := 0
+	for i, item := range items {
+		ctx := &operation.Context{
+			Vars: vars.Merge(operation.Vars{"item": item, "loop_index": i}),
+		}
+		res, skipped, attempts, err := e.runOnce(t, h, op, ctx)
+		r.items = append(r.items, itemResult{
+			item:     formatItem(item),
+			changed:  operation.Changed(res),
+			skipped:  skipped,
+			res:      res,
+			attempts: attempts,
+			err:      err,
+		})
+		r.skipped = r.skipped && skipped && err == nil
+		r.changed = r.changed || operation.Changed(res)
+		if err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+
+		v := resultVars(res)
+		v["Item"] = item
+		v["Skipped"] = skipped
+		v["Failed"] = err != nil
+		results = append(results, v)
+	}
+
+	if op.Register() != "" {
+		e.registered.register(h, op.Register(), map[string]any{"Results": results})
+	}
+	if firstErr != nil {
+		return r, fmt.Errorf("task %q on %s: %d of %d loop items failed: %w", op.Name(), h, failed, len(items), firstErr)
+	}
+	return r, nil
+}
+
+// runOnce runs op on host h unless its when condition is false, or the
+// executor is in check mode and op does not support it, in which case it
+// reports the operation as skipped. Operations with retries are
+// attempted until they succeed and meet their until condition, which can
+// refer to the attempt's result as {{ .result }}. The unsuccessful attempts
+// are returned along with the final result.
+func (e Executor) runOnce(t remote.Transport, h remote.Host, op operation.Operation, ctx *operation.Context) (*gtypes.OrderedMap[string, string], bool, []attempt, error) {
+	ctx.Check, ctx.Diff, ctx.Become = e.optCheck, e.optDiff, e.become
+	if op.When() != "" {
+		ok, err := expr.EvalBool(op.When(), ctx.Vars)
+		if err != nil {
+			return nil, false, nil, fmt.Errorf("task %q on %s: when: %w", op.Name(), h, err)
+		}
+		if !ok {
+			return nil, true, nil, nil
+		}
+	}
+
+	if e.optCheck {
+		if c, ok := op.(operation.Checker); !ok || !c.SupportsCheck() {
+			return nil, true, nil, nil
+		}
+	}
+
+	retry := op.Retry()
+	var attempts []attempt
+	for {
+		res, err := op.Execute(t, ctx)
+		if err == nil && retry.Until != "" {
+			vars := operation.Vars{"result": resultVars(res)}
+			if op.Register() != "" {
+				vars[op.Register()] = vars["result"]
+			}
+			ok, evalErr := expr.EvalBool(retry.Until, ctx.Vars.Merge(vars))
+			if evalErr != nil {
+				return res, false, attempts, fmt.Errorf("task %q on %s: until: %w", op.Name(), h, evalErr)
+			}
+			if !ok {
+				err = fmt.Errorf("until %q is false", retry.Until)
+			}
+		}
+		if err == nil {
+			return res, false, attempts, nil
+		}
+		if len(attempts)+1 >= retry.Retries {
+			if len(attempts) > 0 {
+				err = fmt.Errorf("task %q on %s: failed after %d attempts: %w",
+					op.Name(), h, len(attempts)+1, err)
+			} else if retry.Until != "" {
+				err = fmt.Errorf("task %q on %s: %w", op.Name(), h, err)
+			}
+			return res, false, attempts, err
+		}
+
+		attempts = append(attempts, attempt{res: res, err: err})
+		time.Sleep(retry.Delay)
+	}
+}
+
+// validateLoop checks that loop is a list or an expression referring to one.
+func validateLoop(loop any) error {
+	switch l := loop.(type) {
+	case nil, []any:
+		return nil
+	case string:
+		_, err := expr.Parse(l)
+		return err
+	}
+	return fmt.Errorf("loop must be a list or a variable reference, got %T", loop)
+}
+
+// loopItems returns the items of a loop for the context. String items of a
+// list are rendered as templates. A variable reference must evaluate to a
+// list, or to a string that is split into its non-empty lines.
+func loopItems(loop any, ctx *operation.Context) ([]any, error) {
+	if l, ok := loop.([]any); ok {
+		items := make([]any, 0, len(l))
+		for _, item := range l {
+			if s, ok := item.(string); ok {
+				item = ctx.Render(s)
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	}
+
+	ref, err := expr.Parse(loop.(string))
+	if err != nil {
+		return nil, err
+	}
+	v, err := ref.Eval(ctx.Vars)
+	if err != nil {
+		return nil, err
+	}
+
+	if s, ok := v.(string); ok {
+		var items []any
+		for _, line := range strings.Split(s, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				items = append(items, line)
+			}
+		}
+		return items, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("%q is a %T, not a list", ref, v)
+	}
+	items := make([]any, 0, rv.Len())
+	for i := range rv.Len() {
+		items = append(items, rv.Index(i).Interface())
+	}
+	return items, nil
+}
+
+// formatItem returns the display form of a loop item.
+func formatItem(item any) string {
+	if s, ok := item.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Sprint(item)
+	}
+	return string(b)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package executor
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/cqroot/minop/pkg/operation"
+	"github.com/cqroot/minop/pkg/remote"
+	"gopkg.in/yaml.v3"
+)
+
+// hostFacts returns the facts of a host in the given role. Facts are prefixed
+// with "minop_" so they do not collide with user variables.
+func hostFacts(role string, h remote.Host) operation.Vars {
+	return operation.Vars{
+		"minop_host":    h.String(),
+		"minop_address": h.Address,
+		"minop_user":    h.User,
+		"minop_port":    h.Port,
+		"minop_role":    role,
+	}
+}
+
+// hostVars returns the variables of a host in the given role for op. Later
+// sources take precedence: role defaults, global vars, group vars, host vars,
+// play vars, task vars, registered results, extra vars and finally the
+// minop_ facts.
+func (e Executor) hostVars(pb *Playbook, role string, h remote.Host, op operation.Operation) operation.Vars {
+	return op.Defaults().Merge(
+		pb.Vars,
+		pb.GroupVars[role],
+		pb.HostVars[h],
+		pb.playVars,
+		op.Vars(),
+		e.registered.get(h),
+		e.optExtraVars,
+		hostFacts(role, h),
+	)
+}
+
+// varNameRegexp matches names that can be referenced as {{ .name }} in templates.
+var varNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ParseExtraVars parses extra variables given on the command line. Each
+// argument is either "<name>=<value>" or "@<file>" naming a YAML file of variables.
+// Later arguments take precedence.
+func ParseExtraVars(args []string) (operation.Vars, error) {
+	vars := operation.Vars{}
+	for _, arg := range args {
+		if filename, ok := strings.CutPrefix(arg, "@"); ok {
+			content, err := os.ReadFile(filename)
+			if err != nil {
+				return nil, err
+			}
+
+			var fileVars operation.Vars
+			if err := yaml.Unmarshal(content, &fileVars); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal extra vars file %s: %w", filename, err)
+			}
+			vars = vars.Merge(fileVars)
+			continue
+		}
+
+		name, val, ok := strings.Cut(arg, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid extra var %q, expected <name>=<value> or @<file>", arg)
+		}
+		vars[name] = extraVarValue(val)
+	}
+	return vars, nil
+}
+
+// extraVarValue decodes the value of a "<name>=<value>" extra variable as a
+// YAML scalar, so that debug=false is a bool as it would be in a vars file.
+// Values that are not a single scalar are kept as strings.
+func extraVarValue(val string) any {
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(val), &node); err != nil ||
+		len(node.Content) != 1 || node.Content[0].Kind != yaml.ScalarNode {
+		return val
+	}
+
+	var v any
+	if err := node.Content[0].Decode(&v); err != nil {
+		return val
+	}
+	return v
+}
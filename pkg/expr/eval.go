@@ -0,0 +1,290 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type literalNode struct {
+	val any
+}
+
+func (n literalNode) eval(map[string]any) (any, error) {
+	return n.val, nil
+}
+
+type listNode struct {
+	items []node
+}
+
+func (n listNode) eval(vars map[string]any) (any, error) {
+	list := make([]any, 0, len(n.items))
+	for _, item := range n.items {
+		v, err := item.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+type varNode struct {
+	name string
+}
+
+func (n varNode) eval(vars map[string]any) (any, error) {
+	v, ok := vars[n.name]
+	if !ok {
+		return nil, fmt.Errorf("variable %q is %w", n.name, ErrUndefined)
+	}
+	return v, nil
+}
+
+type indexNode struct {
+	x, index node
+}
+
+func (n indexNode) eval(vars map[string]any) (any, error) {
+	x, err := n.x.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := n.index.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := reflect.ValueOf(x)
+	switch rv.Kind() {
+	case reflect.Map:
+		key, ok := idx.(string)
+		if !ok || rv.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("invalid map key %v", idx)
+		}
+		v := rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))
+		if !v.IsValid() {
+			return nil, fmt.Errorf("field %q is %w", key, ErrUndefined)
+		}
+		return v.Interface(), nil
+	case reflect.Slice, reflect.Array:
+		f, ok := number(idx)
+		if !ok || f != float64(int(f)) {
+			return nil, fmt.Errorf("invalid list index %v", idx)
+		}
+		i := int(f)
+		if i < 0 {
+			i += rv.Len()
+		}
+		if i < 0 || i >= rv.Len() {
+			return nil, fmt.Errorf("index %v is %w", idx, ErrUndefined)
+		}
+		return rv.Index(i).Interface(), nil
+	}
+	return nil, fmt.Errorf("cannot index %T", x)
+}
+
+type definedNode struct {
+	x node
+}
+
+func (n definedNode) eval(vars map[string]any) (any, error) {
+	_, err := n.x.eval(vars)
+	if err != nil {
+		if errors.Is(err, ErrUndefined) {
+			return false, nil
+		}
+		return nil, err
+	}
+	return true, nil
+}
+
+type notNode struct {
+	x node
+}
+
+func (n notNode) eval(vars map[string]any) (any, error) {
+	v, err := n.x.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	return !Truthy(v), nil
+}
+
+type andNode struct {
+	left, right node
+}
+
+func (n andNode) eval(vars map[string]any) (any, error) {
+	v, err := n.left.eval(vars)
+	if err != nil || !Truthy(v) {
+		return false, err
+	}
+	v, err = n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	return Truthy(v), nil
+}
+
+type orNode struct {
+	left, right node
+}
+
+func (n orNode) eval(vars map[string]any) (any, error) {
+	v, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	if Truthy(v) {
+		return true, nil
+	}
+	v, err = n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	return Truthy(v), nil
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n compareNode) eval(vars map[string]any) (any, error) {
+	l, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	case "in":
+		return contains(r, l)
+	case "not in":
+		ok, err := contains(r, l)
+		return !ok, err
+	}
+
+	c, err := compare(l, r)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+// operands converts a pair of values to numbers if one is a number and the
+// other is a number or a string holding one.
+func operands(l, r any) (float64, float64, bool) {
+	ln, lok := number(l)
+	rn, rok := number(r)
+	if lok && rok {
+		return ln, rn, true
+	}
+	if lok {
+		rn, rok = numericString(r)
+	} else if rok {
+		ln, lok = numericString(l)
+	}
+	return ln, rn, lok && rok
+}
+
+// equal reports whether two values are equal.
+func equal(l, r any) bool {
+	if ln, rn, ok := operands(l, r); ok {
+		return ln == rn
+	}
+	return reflect.DeepEqual(l, r)
+}
+
+// compare orders two numbers, or two strings lexically unless both hold numbers.
+func compare(l, r any) (int, error) {
+	ln, rn, ok := operands(l, r)
+	if !ok {
+		ls, lok := l.(string)
+		rs, rok := r.(string)
+		if !lok || !rok {
+			return 0, fmt.Errorf("cannot compare %T with %T", l, r)
+		}
+		ln, lok = numericString(ls)
+		rn, rok = numericString(rs)
+		if !lok || !rok {
+			return strings.Compare(ls, rs), nil
+		}
+	}
+	switch {
+	case ln < rn:
+		return -1, nil
+	case ln > rn:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// contains reports whether the list holds v, the map has the key v, or the
+// string has the substring v.
+func contains(container, v any) (bool, error) {
+	switch c := container.(type) {
+	case string:
+		s, ok := v.(string)
+		if !ok {
+			s = fmt.Sprint(v)
+		}
+		return strings.Contains(c, s), nil
+	case nil:
+		return false, nil
+	}
+
+	rv := reflect.ValueOf(container)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := range rv.Len() {
+			if equal(rv.Index(i).Interface(), v) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case reflect.Map:
+		key, ok := v.(string)
+		if !ok || rv.Type().Key().Kind() != reflect.String {
+			return false, nil
+		}
+		return rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key())).IsValid(), nil
+	}
+	return false, fmt.Errorf("cannot use in with %T", container)
+}
@@ -0,0 +1,159 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package expr implements the small expression language of task conditions
+// such as `when: result.ExitStatus == 0 and minop_role != "db"`.
+//
+// Expressions support string, number, boolean and list literals, variable
+// references with field access (a.b) and indexing (a[0]), the comparison
+// operators ==, !=, <, <=, >, >=, in and not in, the tests "is defined" and
+// "is not defined", and the boolean operators and, or and not (also written
+// &&, || and !). Strings that look like numbers compare as numbers with
+// numeric operands. Expressions cannot call functions or modify variables.
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrUndefined is returned when an expression references an undefined variable or field.
+var ErrUndefined = errors.New("undefined")
+
+// node is a node of a parsed expression.
+type node interface {
+	eval(vars map[string]any) (any, error)
+}
+
+// Expr is a parsed expression.
+type Expr struct {
+	src  string
+	root node
+}
+
+// Parse parses the expression.
+func Parse(s string) (*Expr, error) {
+	toks, err := lex(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", s, err)
+	}
+
+	p := &parser{toks: toks}
+	root, err := p.parseOr()
+	if err == nil && p.peek().kind != tokEOF {
+		err = fmt.Errorf("unexpected %q at position %d", p.peek().text, p.peek().pos)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", s, err)
+	}
+	return &Expr{src: s, root: root}, nil
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Eval evaluates the expression against the variables.
+func (e *Expr) Eval(vars map[string]any) (any, error) {
+	v, err := e.root.eval(vars)
+	if err != nil {
+		return nil, fmt.Errorf("evaluate %q: %w", e.src, err)
+	}
+	return v, nil
+}
+
+// Bool evaluates the expression and reports whether the result is truthy.
+func (e *Expr) Bool(vars map[string]any) (bool, error) {
+	v, err := e.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	return Truthy(v), nil
+}
+
+// EvalBool parses the expression and reports whether it is truthy for the variables.
+func EvalBool(s string, vars map[string]any) (bool, error) {
+	e, err := Parse(s)
+	if err != nil {
+		return false, err
+	}
+	return e.Bool(vars)
+}
+
+// Truthy reports whether v counts as true in a condition. Nil, false, zero
+// numbers, empty strings, empty lists and empty maps are false.
+func Truthy(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case []any:
+		return len(v) > 0
+	case map[string]any:
+		return len(v) > 0
+	}
+	if n, ok := number(v); ok {
+		return n != 0
+	}
+	return true
+}
+
+// number converts numeric values to float64.
+func number(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// numericString converts a string that holds a number to float64.
+func numericString(v any) (float64, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	return n, err == nil
+}
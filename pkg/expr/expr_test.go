@@ -0,0 +1,89 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package expr_test
+
+import (
+	"testing"
+
+	"github.com/cqroot/minop/pkg/expr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvalBool(t *testing.T) {
+	vars := map[string]any{
+		"role":     "web",
+		"port":     8080,
+		"enabled":  true,
+		"packages": []any{"nginx", "curl"},
+		"result":   map[string]any{"ExitStatus": "0", "Stdout": "version 1.2\n"},
+		"empty":    "",
+	}
+
+	for _, tc := range []struct {
+		expr string
+		want bool
+	}{
+		{`role == "web"`, true},
+		{`role != 'web'`, false},
+		{`port >= 8000 and port < 9000`, true},
+		{`result.ExitStatus == 0`, true},
+		{`result["ExitStatus"] != 0`, false},
+		{`"1.2" in result.Stdout`, true},
+		{`"vim" in packages`, false},
+		{`"vim" not in packages`, true},
+		{`packages[0] == "nginx"`, true},
+		{`not enabled || role == "db"`, false},
+		{`!(role == "db" or port == 80)`, true},
+		{`empty`, false},
+		{`missing is defined`, false},
+		{`result.Stderr is not defined`, true},
+		{`missing is defined and missing == 1`, false},
+		{`"10" > "9"`, true},
+		{`"b" > "a"`, true},
+		{`[1, 2] == [1, 2]`, true},
+		{`enabled == true`, true},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			got, err := expr.EvalBool(tc.expr, vars)
+			require.Nil(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestEvalBoolErrors(t *testing.T) {
+	vars := map[string]any{"port": 22}
+
+	for _, s := range []string{
+		`port ==`,
+		`(port == 22`,
+		`port = 22`,
+		`"unterminated`,
+		`port == 22 22`,
+		`and`,
+	} {
+		_, err := expr.Parse(s)
+		require.NotNil(t, err, s)
+	}
+
+	_, err := expr.EvalBool(`missing == 1`, vars)
+	require.ErrorIs(t, err, expr.ErrUndefined)
+
+	_, err = expr.EvalBool(`port < "abc"`, vars)
+	require.NotNil(t, err)
+}
@@ -0,0 +1,229 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package expr
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// parser is a recursive descent parser over the tokens of an expression.
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is a punctuator or keyword in texts.
+func (p *parser) accept(texts ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokPunct && t.kind != tokIdent {
+		return "", false
+	}
+	for _, text := range texts {
+		if t.text == text {
+			p.pos++
+			return text, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(text string) error {
+	if _, ok := p.accept(text); !ok {
+		return unexpected(p.peek())
+	}
+	return nil
+}
+
+// unexpected returns the syntax error for the token t.
+func unexpected(t token) error {
+	if t.kind == tokEOF {
+		return fmt.Errorf("unexpected end of expression")
+	}
+	return fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("or", "||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("and", "&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	if _, ok := p.accept("not", "!"); ok {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{x}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := p.accept("is"); ok {
+		_, negate := p.accept("not")
+		if err := p.expect("defined"); err != nil {
+			return nil, err
+		}
+		var n node = definedNode{left}
+		if negate {
+			n = notNode{n}
+		}
+		return n, nil
+	}
+
+	op, ok := p.accept("==", "!=", "<", "<=", ">", ">=", "in")
+	if !ok {
+		if p.peek().text != "not" || p.toks[p.pos+1].text != "in" {
+			return left, nil
+		}
+		p.pos += 2
+		op = "not in"
+	}
+
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	return compareNode{op: op, left: left, right: right}, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+
+	var n node
+	switch {
+	case t.kind == tokNumber:
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", t.text, t.pos)
+		}
+		return literalNode{v}, nil
+	case t.kind == tokString:
+		return literalNode{t.text}, nil
+	case t.kind == tokIdent:
+		switch t.text {
+		case "true", "True":
+			return literalNode{true}, nil
+		case "false", "False":
+			return literalNode{false}, nil
+		case "none", "None", "null":
+			return literalNode{nil}, nil
+		case "and", "or", "not", "in", "is", "defined":
+			return nil, unexpected(t)
+		}
+		n = varNode{t.text}
+	case t.text == "(":
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return x, nil
+	case t.text == "[":
+		var items []node
+		for {
+			if _, ok := p.accept("]"); ok {
+				return listNode{items}, nil
+			}
+			if len(items) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, x)
+		}
+	default:
+		return nil, unexpected(t)
+	}
+
+	for {
+		if _, ok := p.accept("."); ok {
+			t := p.next()
+			if t.kind != tokIdent {
+				return nil, unexpected(t)
+			}
+			n = indexNode{n, literalNode{t.text}}
+			continue
+		}
+		if _, ok := p.accept("["); ok {
+			idx, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			n = indexNode{n, idx}
+			continue
+		}
+		return n, nil
+	}
+}
@@ -17,18 +17,66 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 
 package operation
 
+import "time"
+
+// Retry configures how often an operation is attempted on a host.
+type Retry struct {
+	Retries int           // Maximum number of attempts, 0 to run once
+	Delay   time.Duration // Pause between attempts
+	Until   string        // Condition an attempt's result must meet to succeed
+}
+
 // baseOperation defines the interface for common operation properties.
 type baseOperation interface {
 	Name() string
 	SetName(string)
 	Role() string
 	SetRole(string)
+	DelegateTo() string
+	SetDelegateTo(string)
+	RunOnce() bool
+	SetRunOnce(bool)
+	Vars() Vars
+	SetVars(Vars)
+	Register() string
+	SetRegister(string)
+	When() string
+	SetWhen(string)
+	Loop() any
+	SetLoop(any)
+	Retry() Retry
+	SetRetry(Retry)
+	Notify() []string
+	SetNotify([]string)
+	Tags() []string
+	SetTags([]string)
+	ID() string
+	SetID(string)
+	DependsOn() []string
+	SetDependsOn([]string)
+	Source() string
+	SetSource(string)
+	Defaults() Vars
+	SetDefaults(Vars)
 }
 
 // baseOperationImpl provides a base implementation for operations.
 type baseOperationImpl struct {
-	name string
-	role string
+	name       string
+	role       string
+	delegateTo string
+	runOnce    bool
+	vars       Vars
+	register   string
+	when       string
+	loop       any
+	retry      Retry
+	notify     []string
+	tags       []string
+	id         string
+	dependsOn  []string
+	source     string
+	defaults   Vars
 }
 
 // Name returns the operation's name.
@@ -50,3 +98,141 @@ func (op baseOperationImpl) Role() string {
 func (op *baseOperationImpl) SetRole(role string) {
 	op.role = role
 }
+
+// DelegateTo returns the host, group or "local" the operation is delegated
+// to, or "" if it runs on the target host itself.
+func (op baseOperationImpl) DelegateTo() string {
+	return op.delegateTo
+}
+
+// SetDelegateTo sets the host the operation is delegated to.
+func (op *baseOperationImpl) SetDelegateTo(delegateTo string) {
+	op.delegateTo = delegateTo
+}
+
+// RunOnce reports whether the operation runs on a single host, sharing its
+// result with the other hosts.
+func (op baseOperationImpl) RunOnce() bool {
+	return op.runOnce
+}
+
+// SetRunOnce sets whether the operation runs on a single host.
+func (op *baseOperationImpl) SetRunOnce(runOnce bool) {
+	op.runOnce = runOnce
+}
+
+// Vars returns the operation's own variables.
+func (op baseOperationImpl) Vars() Vars {
+	return op.vars
+}
+
+// SetVars sets the operation's own variables.
+func (op *baseOperationImpl) SetVars(vars Vars) {
+	op.vars = vars
+}
+
+// Register returns the variable name the operation's result is stored under,
+// or "" if it is not registered.
+func (op baseOperationImpl) Register() string {
+	return op.register
+}
+
+// SetRegister sets the variable name the operation's result is stored under.
+func (op *baseOperationImpl) SetRegister(register string) {
+	op.register = register
+}
+
+// When returns the condition deciding whether the operation runs on a host,
+// or "" if it always runs.
+func (op baseOperationImpl) When() string {
+	return op.when
+}
+
+// SetWhen sets the condition deciding whether the operation runs on a host.
+func (op *baseOperationImpl) SetWhen(when string) {
+	op.when = when
+}
+
+// Loop returns the list or variable reference the operation loops over,
+// or nil if it runs once.
+func (op baseOperationImpl) Loop() any {
+	return op.loop
+}
+
+// SetLoop sets the list or variable reference the operation loops over.
+func (op *baseOperationImpl) SetLoop(loop any) {
+	op.loop = loop
+}
+
+// Retry returns the retry settings of the operation.
+func (op baseOperationImpl) Retry() Retry {
+	return op.retry
+}
+
+// SetRetry sets the retry settings of the operation.
+func (op *baseOperationImpl) SetRetry(retry Retry) {
+	op.retry = retry
+}
+
+// Notify returns the names of the handlers to run when the operation changes a host.
+func (op baseOperationImpl) Notify() []string {
+	return op.notify
+}
+
+// SetNotify sets the names of the handlers to run when the operation changes a host.
+func (op *baseOperationImpl) SetNotify(notify []string) {
+	op.notify = notify
+}
+
+// Tags returns the tags of the operation, used to select tasks to run.
+func (op baseOperationImpl) Tags() []string {
+	return op.tags
+}
+
+// SetTags sets the tags of the operation.
+func (op *baseOperationImpl) SetTags(tags []string) {
+	op.tags = tags
+}
+
+// ID returns the identifier other operations refer to the operation by in
+// their dependencies, or "" if it has none.
+func (op baseOperationImpl) ID() string {
+	return op.id
+}
+
+// SetID sets the identifier of the operation.
+func (op *baseOperationImpl) SetID(id string) {
+	op.id = id
+}
+
+// DependsOn returns the identifiers of the operations the operation depends
+// on, or nil if it depends on all operations before it.
+func (op baseOperationImpl) DependsOn() []string {
+	return op.dependsOn
+}
+
+// SetDependsOn sets the identifiers of the operations the operation depends on.
+func (op *baseOperationImpl) SetDependsOn(dependsOn []string) {
+	op.dependsOn = dependsOn
+}
+
+// Source returns where the operation is defined, as "file:line".
+func (op baseOperationImpl) Source() string {
+	return op.source
+}
+
+// SetSource sets where the operation is defined.
+func (op *baseOperationImpl) SetSource(source string) {
+	op.source = source
+}
+
+// Defaults returns the default variables of the operation, which all other
+// variables take precedence over.
+func (op baseOperationImpl) Defaults() Vars {
+	return op.defaults
+}
+
+// SetDefaults sets the default variables of the operation.
+func (op *baseOperationImpl) SetDefaults(defaults Vars) {
+	op.defaults = defaults
+}
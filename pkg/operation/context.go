@@ -0,0 +1,70 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package operation
+
+import (
+	"maps"
+	"strings"
+)
+
+// Vars holds the variables available to templates, keyed by name.
+type Vars map[string]any
+
+// Merge returns a new Vars with the entries of v overridden by those of others, in order.
+func (v Vars) Merge(others ...Vars) Vars {
+	ret := make(Vars, len(v))
+	maps.Copy(ret, v)
+	for _, other := range others {
+		maps.Copy(ret, other)
+	}
+	return ret
+}
+
+// Context carries the per-host data an operation is executed with.
+type Context struct {
+	// Vars holds the facts and variables of the host.
+	Vars Vars
+	// Check is set in check mode: operations predict whether they would
+	// change the host instead of changing it.
+	Check bool
+	// Diff is set in diff mode: operations that upload files report the
+	// differences between the remote files and their new content.
+	Diff bool
+	// Become is set when commands run as root through sudo.
+	Become bool
+}
+
+// diffing reports whether the operation runs in diff mode.
+func (ctx *Context) diffing() bool {
+	return ctx != nil && ctx.Diff
+}
+
+// checking reports whether the operation runs in check mode.
+func (ctx *Context) checking() bool {
+	return ctx != nil && ctx.Check
+}
+
+// Render expands the template in a task field, such as the shell command or
+// the copy destination, with the variables of the context. Actions that cannot
+// be rendered are left as they are, see RenderField.
+func (ctx *Context) Render(field string) string {
+	if ctx == nil || !strings.Contains(field, "{{") {
+		return field
+	}
+	return RenderField(field, ctx.Vars)
+}
@@ -0,0 +1,153 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package operation
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cqroot/gtypes"
+	"github.com/cqroot/minop/pkg/logs"
+	"github.com/cqroot/minop/pkg/remote"
+)
+
+// execSettings holds the command execution settings shared by operations
+// that run commands: env, chdir, stdin, tty and timeout.
+type execSettings struct {
+	stdin     string
+	stdinFile string
+	execOpts  []remote.ExecOption
+
+	ignoreCheck bool   // Whether the command also runs in check mode
+	check       string // Command predicting whether the command would change the host
+}
+
+// newExecSettings validates the execution settings of the Input.
+func newExecSettings(in Input) (execSettings, error) {
+	if in.Stdin != "" && in.StdinFile != "" {
+		return execSettings{}, fmt.Errorf("%w: stdin and stdin_file are mutually exclusive", ErrInvalidOperation)
+	}
+
+	s := execSettings{
+		stdin:       in.Stdin,
+		stdinFile:   in.StdinFile,
+		ignoreCheck: in.CheckMode != nil && !*in.CheckMode,
+		check:       in.Check,
+	}
+	if s.ignoreCheck && s.check != "" {
+		return execSettings{}, fmt.Errorf("%w: check cannot be set with check_mode false", ErrInvalidOperation)
+	}
+
+	if len(in.Env) > 0 {
+		for name := range in.Env {
+			if err := remote.ValidateEnvName(name); err != nil {
+				return execSettings{}, fmt.Errorf("%w: %w", ErrInvalidOperation, err)
+			}
+		}
+		s.execOpts = append(s.execOpts, remote.WithEnv(in.Env))
+	}
+
+	if in.Chdir != "" {
+		s.execOpts = append(s.execOpts, remote.WithDir(in.Chdir))
+	}
+
+	if in.Tty {
+		modes, err := remote.ParseTerminalModes(in.TtyModes)
+		if err != nil {
+			return execSettings{}, fmt.Errorf("%w: %w", ErrInvalidOperation, err)
+		}
+		s.execOpts = append(s.execOpts, remote.WithPty(remote.PtyConfig{
+			Term:   in.TtyTerm,
+			Width:  in.TtyWidth,
+			Height: in.TtyHeight,
+			Modes:  modes,
+		}))
+	}
+
+	if in.Timeout != "" {
+		timeout, err := time.ParseDuration(in.Timeout)
+		if err != nil {
+			return execSettings{}, fmt.Errorf("%w: invalid timeout: %w", ErrInvalidOperation, err)
+		}
+		s.execOpts = append(s.execOpts, remote.WithTimeout(timeout))
+	}
+
+	return s, nil
+}
+
+// options returns the execution options for a single command run with ctx.
+// The stdin content or file is opened anew for every call; the returned
+// function closes it.
+func (s execSettings) options(ctx *Context) ([]remote.ExecOption, func(), error) {
+	execOpts := s.commandOptions(ctx)
+	closeFn := func() {}
+
+	if s.stdin != "" {
+		execOpts = append(execOpts, remote.WithStdin(strings.NewReader(s.stdin)))
+	} else if s.stdinFile != "" {
+		f, err := os.Open(s.stdinFile)
+		if err != nil {
+			logs.Logger().Err(err).Msg("failed to open stdin file")
+			return nil, nil, err
+		}
+		execOpts = append(execOpts, remote.WithStdin(f))
+		closeFn = func() { _ = f.Close() }
+	}
+
+	return execOpts, closeFn, nil
+}
+
+// commandOptions returns the execution options without stdin for a command
+// run with ctx.
+func (s execSettings) commandOptions(ctx *Context) []remote.ExecOption {
+	execOpts := slices.Clone(s.execOpts)
+	if ctx != nil && ctx.Become {
+		execOpts = append(execOpts, remote.WithBecome())
+	}
+	return execOpts
+}
+
+// SupportsCheck reports whether the command runs in check mode, or a check
+// command predicts its outcome.
+func (s execSettings) SupportsCheck() bool {
+	return s.ignoreCheck || s.check != ""
+}
+
+// runCheck runs the check command instead of the command in check mode. The
+// command would change the host if the check command exits with a non-zero
+// status. Templates in the check command are expanded with the context's variables.
+func (s execSettings) runCheck(t remote.Transport, ctx *Context) (*gtypes.OrderedMap[string, string], error) {
+	cmd := ctx.Render(s.check)
+
+	exitStatus, stdout, stderr, err := t.ExecuteCommand(cmd, s.commandOptions(ctx)...)
+	if err != nil {
+		return nil, err
+	}
+
+	res := gtypes.NewOrderedMap[string, string]()
+	res.Put("Check", cmd)
+	res.Put("ExitStatus", strconv.Itoa(exitStatus))
+	res.Put("Stdout", stdout)
+	res.Put("Stderr", stderr)
+	res.Put(ResultChanged, strconv.FormatBool(exitStatus != 0))
+	return res, nil
+}
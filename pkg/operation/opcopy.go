@@ -20,6 +20,8 @@ package operation
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/cqroot/gtypes"
 	"github.com/cqroot/minop/pkg/logs"
@@ -29,21 +31,20 @@ import (
 // OpCopy copies files or directories to remote hosts via SFTP.
 type OpCopy struct {
 	baseOperationImpl
-	copy   string
-	to     string
-	backup bool
+	uploadSettings
+	copy string
 }
 
 // NewOpCopy creates a new OpCopy operation from the given Input.
-// Returns ErrInvalidOperation if the To field is empty.
+// Returns ErrInvalidOperation if the To field is empty or Mode is invalid.
 func NewOpCopy(in Input) (*OpCopy, error) {
-	if in.To == "" {
-		return nil, MakeErrInvalidOperation(in)
+	settings, err := newUploadSettings(in)
+	if err != nil {
+		return nil, err
 	}
 	return &OpCopy{
-		copy:   in.Copy,
-		to:     in.To,
-		backup: in.Backup,
+		uploadSettings: settings,
+		copy:           in.Copy,
 	}, nil
 }
 
@@ -52,37 +53,39 @@ func (op OpCopy) DefaultName() string {
 	return fmt.Sprintf("[copy] %s => %s", op.copy, op.to)
 }
 
-// Execute uploads the local file or directory to the remote host.
-func (op OpCopy) Execute(r *remote.Remote) (*gtypes.OrderedMap[string, string], error) {
-	if op.backup {
-		logs.Logger().Debug().Str("Dst", op.to).Msg("backup file")
-		ret, stdout, stderr, err := r.ExecuteCommand(fmt.Sprintf(
-			"if [ ! -e '%[1]s.minop_bak' ] && [ -f '%[1]s' ]; then cp -a -- '%[1]s' '%[1]s.minop_bak'; else exit 0; fi", op.to))
-		if err != nil {
-			logs.Logger().Err(err).Msg("failed to back up source file")
-			return nil, err
-		}
-		if ret != 0 {
-			err := fmt.Errorf("command ret: %d, out: %s, err: %s", ret, stdout, stderr)
-			logs.Logger().Err(err).Msg("failed to back up source file")
-			return nil, err
-		}
-	}
+// Execute uploads the local file or directory to the remote host unless the
+// remote copy has the same content already, and reports whether it changed.
+// Templates in the source and destination are expanded with the context's
+// variables. In check mode, it only reports which remote files would change.
+// In diff mode, it reports how the remote files change.
+func (op OpCopy) Execute(t remote.Transport, ctx *Context) (*gtypes.OrderedMap[string, string], error) {
+	src := ctx.Render(op.copy)
+	dst := ctx.Render(op.to)
 
-	fileInfo, err := os.Lstat(op.copy)
+	fileInfo, err := os.Lstat(src)
 	if err != nil {
 		logs.Logger().Err(err).Msg("")
 		return nil, err
 	}
 
 	if fileInfo.Mode()&os.ModeSymlink != 0 {
-		err = fmt.Errorf("%s is a symbolic link", op.copy)
+		err = fmt.Errorf("%s is a symbolic link", src)
 		logs.Logger().Err(err).Msg("")
 		return nil, err
-	} else if fileInfo.IsDir() {
-		err = r.UploadDir(op.copy, op.to)
-	} else {
-		err = r.UploadFile(op.copy, op.to)
+	}
+
+	var (
+		changed bool
+		changes []string
+		d       string
+	)
+	switch {
+	case fileInfo.IsDir() && op.mode != 0:
+		return nil, fmt.Errorf("mode is not supported for directory %s", src)
+	case fileInfo.IsDir():
+		changed, changes, d, err = op.uploadDir(t, src, dst, ctx)
+	default:
+		changed, d, err = op.uploadFile(t, src, dst, ctx)
 	}
 
 	if err != nil {
@@ -90,6 +93,58 @@ func (op OpCopy) Execute(r *remote.Remote) (*gtypes.OrderedMap[string, string],
 	}
 
 	res := gtypes.NewOrderedMap[string, string]()
-	res.Put("Result", fmt.Sprintf("%s -> %s", op.copy, op.to))
+	res.Put("Result", fmt.Sprintf("%s -> %s", src, dst))
+	if ctx.checking() && changes != nil {
+		res.Put("Changes", strings.Join(changes, "\n"))
+	}
+	if d != "" {
+		res.Put(ResultDiff, d)
+	}
+	res.Put(ResultChanged, strconv.FormatBool(changed))
 	return res, nil
 }
+
+// SupportsCheck reports that copy operations support check mode.
+func (op OpCopy) SupportsCheck() bool {
+	return true
+}
+
+// uploadDir uploads the local directory to dst if any of its files is
+// missing or differs on the remote host, and reports whether it did. In check
+// and diff mode, it also returns the remote paths of the differing files and,
+// in diff mode, their diffs. In check mode, it uploads nothing.
+func (op OpCopy) uploadDir(t remote.Transport, src, dst string, ctx *Context) (bool, []string, string, error) {
+	var (
+		differs bool
+		changes []string
+		diffs   strings.Builder
+		err     error
+	)
+	if ctx.checking() || ctx.diffing() {
+		err = walkRemoteDiffs(t, src, dst, func(localPath, remotePath string) error {
+			changes = append(changes, remotePath)
+			if !ctx.diffing() {
+				return nil
+			}
+			d, err := fileDiff(t, localPath, remotePath)
+			diffs.WriteString(d)
+			return err
+		})
+		differs = len(changes) > 0
+	} else {
+		differs, err = remoteDirDiffers(t, src, dst)
+	}
+	if err != nil {
+		return false, nil, "", err
+	}
+	if !differs || ctx.checking() {
+		return differs, changes, diffs.String(), nil
+	}
+
+	if op.backup {
+		if err := op.backupDst(t, dst); err != nil {
+			return false, nil, "", err
+		}
+	}
+	return true, changes, diffs.String(), t.UploadDir(src, dst)
+}
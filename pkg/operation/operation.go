@@ -23,28 +23,103 @@ import (
 
 	"github.com/cqroot/gtypes"
 	"github.com/cqroot/minop/pkg/remote"
+	"gopkg.in/yaml.v3"
 )
 
 // Input defines the YAML input structure for creating operations.
-// It specifies the operation type (shell or copy) and its parameters.
+// It specifies the operation type (shell, copy, script, template or flush_handlers)
+// and its parameters.
 type Input struct {
-	Name string `yaml:"name"`
-	Role string `yaml:"role"`
+	Name       string     `yaml:"name"`
+	Role       string     `yaml:"role"`
+	DelegateTo string     `yaml:"delegate_to"`
+	RunOnce    bool       `yaml:"run_once"`
+	Register   string     `yaml:"register"`
+	When       string     `yaml:"when"`
+	Loop       any        `yaml:"loop"`
+	Retries    int        `yaml:"retries"`
+	Delay      string     `yaml:"delay"`
+	Until      string     `yaml:"until"`
+	Notify     StringList `yaml:"notify"`
+	Tags       StringList `yaml:"tags"`
+	ID         string     `yaml:"id"`
+	DependsOn  StringList `yaml:"depends_on"`
 
-	Shell string `yaml:"shell"`
+	FlushHandlers bool `yaml:"flush_handlers"`
 
-	Copy   string `yaml:"copy"`
-	To     string `yaml:"to"`
-	Backup bool   `yaml:"backup"`
+	Shell string            `yaml:"shell"`
+	Env   map[string]string `yaml:"env"`
+	Chdir string            `yaml:"chdir"`
+
+	Stdin     string `yaml:"stdin"`
+	StdinFile string `yaml:"stdin_file"`
+	Timeout   string `yaml:"timeout"`
+
+	CheckMode *bool  `yaml:"check_mode"`
+	Check     string `yaml:"check"`
+
+	Script      string   `yaml:"script"`
+	Args        []string `yaml:"args"`
+	Interpreter string   `yaml:"interpreter"`
+
+	Tty       bool              `yaml:"tty"`
+	TtyTerm   string            `yaml:"tty_term"`
+	TtyWidth  int               `yaml:"tty_width"`
+	TtyHeight int               `yaml:"tty_height"`
+	TtyModes  map[string]uint32 `yaml:"tty_modes"`
+
+	Copy     string `yaml:"copy"`
+	Template string `yaml:"template"`
+	To       string `yaml:"to"`
+	Backup   bool   `yaml:"backup"`
+	Mode     string `yaml:"mode"`
+
+	Vars Vars `yaml:"vars"`
+}
+
+// StringList is a list of strings that can also be written as a single string in YAML.
+type StringList []string
+
+// UnmarshalYAML accepts a single string or a list of strings.
+func (l *StringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = StringList{value.Value}
+		return nil
+	}
+	return value.Decode((*[]string)(l))
 }
 
 // Operation defines the interface for executable remote operations.
 type Operation interface {
 	baseOperation
-	Execute(r *remote.Remote) (*gtypes.OrderedMap[string, string], error)
+	Execute(t remote.Transport, ctx *Context) (*gtypes.OrderedMap[string, string], error)
 	DefaultName() string
 }
 
+// ResultDiff is the result field holding the unified diff of the files an
+// operation changed, in diff mode.
+const ResultDiff = "Diff"
+
+// Checker is implemented by operations that support check mode. In check
+// mode, operations that do not implement it, or whose SupportsCheck returns
+// false, are skipped; the others are executed with Context.Check set.
+type Checker interface {
+	SupportsCheck() bool
+}
+
+// ResultChanged is the result field reporting whether an operation changed
+// the host: "true" or "false".
+const ResultChanged = "Changed"
+
+// Changed reports whether an operation result says the host was changed.
+func Changed(res *gtypes.OrderedMap[string, string]) bool {
+	if res == nil {
+		return false
+	}
+	v, ok := res.Get(ResultChanged)
+	return ok && v == "true"
+}
+
 // ErrInvalidOperation is returned when an operation cannot be created from Input.
 var ErrInvalidOperation = errors.New("invalid operation")
 
@@ -56,6 +131,10 @@ func MakeErrInvalidOperation(in Input) error {
 // GetOperation creates an Operation from the given Input.
 // It returns an error if the Input is invalid or unsupported.
 func GetOperation(in Input) (Operation, error) {
+	if in.FlushHandlers {
+		return NewOpFlushHandlers(), nil
+	}
+
 	if in.Shell != "" {
 		return NewOpShell(in)
 	}
@@ -64,5 +143,13 @@ func GetOperation(in Input) (Operation, error) {
 		return NewOpCopy(in)
 	}
 
+	if in.Script != "" {
+		return NewOpScript(in)
+	}
+
+	if in.Template != "" {
+		return NewOpTemplate(in)
+	}
+
 	return nil, MakeErrInvalidOperation(in)
 }
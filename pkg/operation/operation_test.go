@@ -0,0 +1,333 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package operation_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cqroot/minop/pkg/operation"
+	"github.com/cqroot/minop/pkg/remote"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpShellExecute(t *testing.T) {
+	fake := remote.NewFake(func(cmd string) (int, string, string, error) {
+		return 3, "out:" + cmd, "err", nil
+	})
+
+	op, err := operation.NewOpShell(operation.Input{Shell: "uptime"})
+	require.Nil(t, err)
+
+	res, err := op.Execute(fake, &operation.Context{})
+	require.Nil(t, err)
+	require.Equal(t, []string{"uptime"}, fake.Commands)
+
+	exitStatus, _ := res.Get("ExitStatus")
+	require.Equal(t, "3", exitStatus)
+	stdout, _ := res.Get("Stdout")
+	require.Equal(t, "out:uptime", stdout)
+	stderr, _ := res.Get("Stderr")
+	require.Equal(t, "err", stderr)
+}
+
+func TestOpShellRaw(t *testing.T) {
+	fake := remote.NewFake(nil)
+	ctx := &operation.Context{Vars: operation.Vars{"v": 1}}
+
+	op, err := operation.NewOpShell(operation.Input{Shell: "echo {{ .v }}"})
+	require.Nil(t, err)
+	_, err = op.Execute(fake, ctx)
+	require.Nil(t, err)
+
+	op.SetRaw(true)
+	_, err = op.Execute(fake, ctx)
+	require.Nil(t, err)
+	require.Equal(t, []string{"echo 1", "echo {{ .v }}"}, fake.Commands)
+}
+
+func TestRenderField(t *testing.T) {
+	vars := operation.Vars{"v": 1, "name": "app"}
+	for _, tc := range []struct {
+		field    string
+		expected string
+	}{
+		{"echo {{ .v }}", "echo 1"},
+		{"docker ps --format '{{.Names}}'", "docker ps --format '{{.Names}}'"},
+		{"docker ps -f name={{ .name }} --format '{{.Names}}'", "docker ps -f name=app --format '{{.Names}}'"},
+		{
+			"kubectl get pods -o go-template='{{range .items}}{{.metadata.name}}{{end}}'",
+			"kubectl get pods -o go-template='{{range .items}}{{.metadata.name}}{{end}}'",
+		},
+		{"awk '{{print $1}}' {{ .name }}", "awk '{{print $1}}' {{ .name }}"},
+		{`echo {{ "{{" }}.Names}}`, "echo {{.Names}}"},
+		{"a {{- .v }} {{ .missing -}} b", "a1 {{ .missing -}} b"},
+		{"{{ .v }} {{ .missing }}", "1 {{ .missing }}"},
+	} {
+		require.Equal(t, tc.expected, operation.RenderField(tc.field, vars), tc.field)
+	}
+}
+
+func TestOpCopyExecute(t *testing.T) {
+	dir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0o644))
+	require.Nil(t, os.MkdirAll(filepath.Join(dir, "sub"), 0o755))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("b"), 0o644))
+
+	fake := remote.NewFake(nil)
+
+	op, err := operation.NewOpCopy(operation.Input{Copy: filepath.Join(dir, "a.txt"), To: "/opt/it's a.txt", Backup: true})
+	require.Nil(t, err)
+	res, err := op.Execute(fake, &operation.Context{})
+	require.Nil(t, err)
+	require.True(t, operation.Changed(res))
+	require.Equal(t, []string{
+		`if [ ! -e '/opt/it'\''s a.txt.minop_bak' ] && [ -f '/opt/it'\''s a.txt' ]; ` +
+			`then cp -a -- '/opt/it'\''s a.txt' '/opt/it'\''s a.txt.minop_bak'; else exit 0; fi`,
+	}, fake.Commands)
+	require.Equal(t, []byte("a"), fake.Files["/opt/it's a.txt"])
+
+	res, err = op.Execute(fake, &operation.Context{})
+	require.Nil(t, err)
+	require.False(t, operation.Changed(res))
+	require.Len(t, fake.Commands, 1)
+
+	// Content of the same size is compared by checksum.
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("b"), 0o644))
+	res, err = op.Execute(fake, &operation.Context{})
+	require.Nil(t, err)
+	require.True(t, operation.Changed(res))
+	require.Equal(t, []byte("b"), fake.Files["/opt/it's a.txt"])
+
+	op, err = operation.NewOpCopy(operation.Input{Copy: dir, To: "/opt/dir"})
+	require.Nil(t, err)
+	res, err = op.Execute(fake, &operation.Context{})
+	require.Nil(t, err)
+	require.True(t, operation.Changed(res))
+	require.Equal(t, []byte("b"), fake.Files["/opt/dir/sub/b.txt"])
+
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("c"), 0o644))
+	res, err = op.Execute(fake, &operation.Context{})
+	require.Nil(t, err)
+	require.True(t, operation.Changed(res))
+	require.Equal(t, []byte("c"), fake.Files["/opt/dir/sub/b.txt"])
+
+	fi, err := fake.Stat("/opt/dir/sub")
+	require.Nil(t, err)
+	require.True(t, fi.IsDir())
+}
+
+func TestCheckMode(t *testing.T) {
+	dir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0o644))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "b.txt"), []byte("b"), 0o644))
+
+	fake := remote.NewFake(func(cmd string) (int, string, string, error) {
+		if cmd == "id bob" {
+			return 1, "", "no such user", nil
+		}
+		return 0, "", "", nil
+	})
+	fake.Files["/opt/dir/a.txt"] = []byte("a")
+	check := &operation.Context{Check: true}
+
+	op, err := operation.NewOpCopy(operation.Input{Copy: dir, To: "/opt/dir"})
+	require.Nil(t, err)
+	res, err := op.Execute(fake, check)
+	require.Nil(t, err)
+	require.True(t, operation.Changed(res))
+	changes, _ := res.Get("Changes")
+	require.Equal(t, "/opt/dir/b.txt", changes)
+	require.NotContains(t, fake.Files, "/opt/dir/b.txt")
+
+	shell, err := operation.NewOpShell(operation.Input{Shell: "useradd bob"})
+	require.Nil(t, err)
+	require.False(t, shell.SupportsCheck())
+
+	shell, err = operation.NewOpShell(operation.Input{Shell: "useradd bob", Check: "id bob"})
+	require.Nil(t, err)
+	require.True(t, shell.SupportsCheck())
+	res, err = shell.Execute(fake, check)
+	require.Nil(t, err)
+	require.True(t, operation.Changed(res))
+	require.Equal(t, []string{"id bob"}, fake.Commands)
+
+	readOnly := false
+	_, err = operation.NewOpShell(operation.Input{Shell: "cat /etc/hosts", CheckMode: &readOnly, Check: "true"})
+	require.ErrorIs(t, err, operation.ErrInvalidOperation)
+}
+
+func TestDiffMode(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "app.conf")
+	require.Nil(t, os.WriteFile(src, []byte("host: 0.0.0.0\nport: 8080\n"), 0o644))
+
+	fake := remote.NewFake(nil)
+	fake.Files["/etc/app.conf"] = []byte("host: 0.0.0.0\nport: 80\n")
+
+	op, err := operation.NewOpCopy(operation.Input{Copy: src, To: "/etc/app.conf"})
+	require.Nil(t, err)
+
+	// With check mode, the diff is shown without uploading the file.
+	res, err := op.Execute(fake, &operation.Context{Check: true, Diff: true})
+	require.Nil(t, err)
+	require.True(t, operation.Changed(res))
+	d, _ := res.Get(operation.ResultDiff)
+	require.Equal(t, `--- /etc/app.conf
++++ /etc/app.conf
+@@ -1,2 +1,2 @@
+ host: 0.0.0.0
+-port: 80
++port: 8080
+`, d)
+	require.Equal(t, "host: 0.0.0.0\nport: 80\n", string(fake.Files["/etc/app.conf"]))
+
+	res, err = op.Execute(fake, &operation.Context{Diff: true})
+	require.Nil(t, err)
+	d2, _ := res.Get(operation.ResultDiff)
+	require.Equal(t, d, d2)
+	require.Equal(t, "host: 0.0.0.0\nport: 8080\n", string(fake.Files["/etc/app.conf"]))
+
+	res, err = op.Execute(fake, &operation.Context{Diff: true})
+	require.Nil(t, err)
+	require.False(t, res.Has(operation.ResultDiff))
+
+	require.Nil(t, os.WriteFile(src, []byte{0x7f, 'E', 'L', 'F', 0}, 0o644))
+	res, err = op.Execute(fake, &operation.Context{Diff: true})
+	require.Nil(t, err)
+	d, _ = res.Get(operation.ResultDiff)
+	require.Equal(t, "Binary file /etc/app.conf differs\n", d)
+}
+
+func TestOpCopyInvalid(t *testing.T) {
+	_, err := operation.NewOpCopy(operation.Input{Copy: "a.txt"})
+	require.ErrorIs(t, err, operation.ErrInvalidOperation)
+}
+
+func TestOpShellStdin(t *testing.T) {
+	_, err := operation.NewOpShell(operation.Input{Shell: "psql", Stdin: "select 1;", StdinFile: "dump.sql"})
+	require.ErrorIs(t, err, operation.ErrInvalidOperation)
+
+	op, err := operation.NewOpShell(operation.Input{Shell: "psql", StdinFile: filepath.Join(t.TempDir(), "missing.sql")})
+	require.Nil(t, err)
+	_, err = op.Execute(remote.NewLocal(), &operation.Context{})
+	require.True(t, os.IsNotExist(err))
+
+	op, err = operation.NewOpShell(operation.Input{Shell: "cat", Stdin: "select 1;"})
+	require.Nil(t, err)
+	for range 2 {
+		res, err := op.Execute(remote.NewLocal(), &operation.Context{})
+		require.Nil(t, err)
+		stdout, _ := res.Get("Stdout")
+		require.Equal(t, "select 1;", stdout)
+	}
+}
+
+func TestOpScriptExecute(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "deploy.sh")
+	require.Nil(t, os.WriteFile(script, []byte("echo deployed"), 0o644))
+
+	var uploaded []byte
+	fake := remote.NewFake(nil)
+	fake.Handler = func(cmd string) (int, string, string, error) {
+		switch {
+		case strings.HasPrefix(cmd, "mktemp "):
+			return 0, "/var/tmp/.minop-Ab3dE6gH9j\n", "", nil
+		case strings.HasPrefix(cmd, "bash -e "):
+			uploaded = fake.Files["/var/tmp/.minop-Ab3dE6gH9j"]
+			return 0, "deployed", "", nil
+		}
+		return 0, "", "", nil
+	}
+
+	op, err := operation.NewOpScript(operation.Input{Script: script, Args: []string{"v1", "it's"}, Interpreter: "bash -e"})
+	require.Nil(t, err)
+	require.Equal(t, "[script] "+script+" v1 it's", op.DefaultName())
+
+	res, err := op.Execute(fake, &operation.Context{})
+	require.Nil(t, err)
+	require.Equal(t, []byte("echo deployed"), uploaded)
+	stdout, _ := res.Get("Stdout")
+	require.Equal(t, "deployed", stdout)
+
+	require.Equal(t, []string{
+		`mktemp "${TMPDIR:-/tmp}/.minop-XXXXXXXXXX"`,
+		`chmod 700 -- '/var/tmp/.minop-Ab3dE6gH9j'`,
+		`bash -e '/var/tmp/.minop-Ab3dE6gH9j' 'v1' 'it'\''s'`,
+		`rm -f -- '/var/tmp/.minop-Ab3dE6gH9j'`,
+	}, fake.Commands)
+
+	// Without a temporary file, nothing is uploaded or removed.
+	failing := remote.NewFake(func(cmd string) (int, string, string, error) {
+		return 1, "", "mktemp: failed to create file", nil
+	})
+	_, err = op.Execute(failing, &operation.Context{})
+	require.ErrorContains(t, err, "failed to create file")
+	require.Len(t, failing.Commands, 1)
+	require.Empty(t, failing.Files)
+}
+
+func TestOpScriptCleanupOnFailure(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "deploy.sh")
+	require.Nil(t, os.WriteFile(script, []byte("sleep 10"), 0o644))
+
+	fake := remote.NewFake(func(cmd string) (int, string, string, error) {
+		switch {
+		case strings.HasPrefix(cmd, "mktemp "):
+			return 0, "/tmp/.minop-Ab3dE6gH9j\n", "", nil
+		case strings.HasPrefix(cmd, "'/tmp/"):
+			return 0, "", "", remote.ErrCommandTimeout
+		}
+		return 0, "", "", nil
+	})
+
+	op, err := operation.NewOpScript(operation.Input{Script: script, Timeout: "1s"})
+	require.Nil(t, err)
+
+	_, err = op.Execute(fake, &operation.Context{})
+	require.ErrorIs(t, err, remote.ErrCommandTimeout)
+	require.Len(t, fake.Commands, 4)
+	require.Equal(t, "rm -f -- '/tmp/.minop-Ab3dE6gH9j'", fake.Commands[3])
+
+	_, err = operation.NewOpScript(operation.Input{Script: script, Timeout: "soon"})
+	require.ErrorIs(t, err, operation.ErrInvalidOperation)
+}
+
+func TestOpTemplateExecute(t *testing.T) {
+	tmpl := filepath.Join(t.TempDir(), "app.conf.tmpl")
+	require.Nil(t, os.WriteFile(tmpl, []byte(
+		"node={{ .node_id }} host={{ .minop_address }} role={{ upper .minop_role }} "+
+			"log={{ index . \"log_level\" | default \"info\" }}\n"), 0o644))
+
+	fake := remote.NewFake(nil)
+	op, err := operation.NewOpTemplate(operation.Input{Template: tmpl, To: "/etc/app.conf", Mode: "0600"})
+	require.Nil(t, err)
+
+	ctx := &operation.Context{Vars: operation.Vars{"node_id": 3, "minop_address": "10.0.0.3", "minop_role": "web"}}
+	_, err = op.Execute(fake, ctx)
+	require.Nil(t, err)
+	require.Equal(t, "node=3 host=10.0.0.3 role=WEB log=info\n", string(fake.Files["/etc/app.conf"]))
+	require.Equal(t, os.FileMode(0o600), fake.Modes["/etc/app.conf"])
+
+	_, err = op.Execute(fake, &operation.Context{Vars: operation.Vars{}})
+	require.ErrorContains(t, err, "node_id")
+
+	_, err = operation.NewOpTemplate(operation.Input{Template: tmpl, To: "/etc/app.conf", Mode: "rw"})
+	require.ErrorIs(t, err, operation.ErrInvalidOperation)
+}
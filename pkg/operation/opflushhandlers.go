@@ -0,0 +1,45 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package operation
+
+import (
+	"github.com/cqroot/gtypes"
+	"github.com/cqroot/minop/pkg/remote"
+)
+
+// OpFlushHandlers marks the point in the task list where the handlers notified
+// so far are run. The executor runs the handlers; executing the operation
+// itself does nothing.
+type OpFlushHandlers struct {
+	baseOperationImpl
+}
+
+// NewOpFlushHandlers creates a new OpFlushHandlers operation.
+func NewOpFlushHandlers() *OpFlushHandlers {
+	return &OpFlushHandlers{}
+}
+
+// DefaultName returns the default name for flush_handlers operations.
+func (op OpFlushHandlers) DefaultName() string {
+	return "[flush_handlers]"
+}
+
+// Execute does nothing.
+func (op OpFlushHandlers) Execute(t remote.Transport, ctx *Context) (*gtypes.OrderedMap[string, string], error) {
+	return gtypes.NewOrderedMap[string, string](), nil
+}
@@ -0,0 +1,161 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package operation
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/cqroot/gtypes"
+	"github.com/cqroot/minop/pkg/logs"
+	"github.com/cqroot/minop/pkg/remote"
+)
+
+// scriptTempCommand creates an empty file for the uploaded script on the remote
+// host and prints its path. It honors TMPDIR, so that hosts whose /tmp is
+// mounted noexec can point it to another directory.
+const scriptTempCommand = `mktemp "${TMPDIR:-/tmp}/.minop-XXXXXXXXXX"`
+
+// OpScript uploads a local script to remote hosts, runs it and removes it.
+type OpScript struct {
+	baseOperationImpl
+	execSettings
+	script      string
+	args        []string
+	interpreter string
+}
+
+// NewOpScript creates a new OpScript operation from the given Input.
+// Returns ErrInvalidOperation if Script field is empty or the execution
+// settings (env, stdin, tty, timeout) are invalid.
+func NewOpScript(in Input) (*OpScript, error) {
+	if in.Script == "" {
+		return nil, MakeErrInvalidOperation(in)
+	}
+
+	settings, err := newExecSettings(in)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OpScript{
+		execSettings: settings,
+		script:       in.Script,
+		args:         in.Args,
+		interpreter:  in.Interpreter,
+	}, nil
+}
+
+// DefaultName returns the default name for script operations.
+func (op OpScript) DefaultName() string {
+	if len(op.args) == 0 {
+		return fmt.Sprintf("[script] %s", op.script)
+	}
+	return fmt.Sprintf("[script] %s %s", op.script, strings.Join(op.args, " "))
+}
+
+// command returns the command line that runs the script uploaded to remotePath.
+// Templates in the arguments are expanded with the context's variables.
+func (op OpScript) command(ctx *Context, remotePath string) string {
+	words := make([]string, 0, len(op.args)+2)
+	if op.interpreter != "" {
+		words = append(words, op.interpreter)
+	}
+	words = append(words, remote.ShellQuote(remotePath))
+	for _, arg := range op.args {
+		words = append(words, remote.ShellQuote(ctx.Render(arg)))
+	}
+	return strings.Join(words, " ")
+}
+
+// Execute uploads the script to a temporary file created on the remote host,
+// makes it executable and runs it. The uploaded script is removed afterwards,
+// even if the upload, the command or its timeout fails. Templates in the
+// script path and arguments are expanded with the context's variables. In
+// check mode, the check command runs instead unless check_mode is false.
+func (op OpScript) Execute(t remote.Transport, ctx *Context) (*gtypes.OrderedMap[string, string], error) {
+	if ctx.checking() && !op.ignoreCheck {
+		return op.runCheck(t, ctx)
+	}
+
+	src := ctx.Render(op.script)
+
+	execOpts, closeStdin, err := op.options(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeStdin()
+
+	remotePath, err := scriptTempFile(t)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		logs.Logger().Debug().Str("path", remotePath).Msg("remove script")
+		ret, _, stderr, err := t.ExecuteCommand("rm -f -- " + remote.ShellQuote(remotePath))
+		if err != nil || ret != 0 {
+			logs.Logger().Error().Err(err).Str("path", remotePath).Str("stderr", stderr).Msg("failed to remove script")
+		}
+	}()
+
+	if err := t.UploadFile(src, remotePath); err != nil {
+		return nil, err
+	}
+
+	cmd := op.command(ctx, remotePath)
+
+	ret, stdout, stderr, err := t.ExecuteCommand("chmod 700 -- " + remote.ShellQuote(remotePath))
+	if err != nil {
+		return nil, err
+	}
+	if ret != 0 {
+		err := fmt.Errorf("command ret: %d, out: %s, err: %s", ret, stdout, stderr)
+		logs.Logger().Err(err).Msg("failed to make script executable")
+		return nil, err
+	}
+
+	exitStatus, stdout, stderr, err := t.ExecuteCommand(cmd, execOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	res := gtypes.NewOrderedMap[string, string]()
+	res.Put("ExitStatus", strconv.Itoa(exitStatus))
+	res.Put("Stdout", stdout)
+	res.Put("Stderr", stderr)
+	res.Put(ResultChanged, "true")
+	return res, nil
+}
+
+// scriptTempFile creates a unique temporary file on the remote host with
+// mktemp and returns its path.
+func scriptTempFile(t remote.Transport) (string, error) {
+	ret, stdout, stderr, err := t.ExecuteCommand(scriptTempCommand)
+	if err != nil {
+		return "", err
+	}
+	remotePath := strings.TrimSpace(stdout)
+	if ret != 0 || !path.IsAbs(remotePath) {
+		err := fmt.Errorf("command ret: %d, out: %s, err: %s", ret, stdout, stderr)
+		logs.Logger().Err(err).Msg("failed to create temporary script file")
+		return "", err
+	}
+	return remotePath, nil
+}
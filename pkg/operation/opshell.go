@@ -28,28 +28,63 @@ import (
 // OpShell executes shell commands on remote hosts.
 type OpShell struct {
 	baseOperationImpl
+	execSettings
 	shell string
+	raw   bool // Whether the command runs as given, without expanding templates
 }
 
 // NewOpShell creates a new OpShell operation from the given Input.
-// Returns ErrInvalidOperation if Shell field is empty.
+// Returns ErrInvalidOperation if Shell field is empty or the execution
+// settings (env, stdin, tty, timeout) are invalid.
 func NewOpShell(in Input) (*OpShell, error) {
 	if in.Shell == "" {
 		return nil, MakeErrInvalidOperation(in)
 	}
+
+	settings, err := newExecSettings(in)
+	if err != nil {
+		return nil, err
+	}
+
 	return &OpShell{
-		shell: in.Shell,
+		execSettings: settings,
+		shell:        in.Shell,
 	}, nil
 }
 
+// SetRaw sets whether the command runs as given, without expanding templates,
+// as for commands typed in the interactive CLI.
+func (op *OpShell) SetRaw(raw bool) {
+	op.raw = raw
+}
+
 // DefaultName returns the default name for shell operations.
 func (op OpShell) DefaultName() string {
 	return fmt.Sprintf("[shell] %s", op.shell)
 }
 
 // Execute runs the shell command on the remote host and returns the results.
-func (op OpShell) Execute(r *remote.Remote) (*gtypes.OrderedMap[string, string], error) {
-	exitStatus, stdout, stderr, err := r.ExecuteCommand(op.shell)
+// Templates in the command are expanded with the context's variables unless it
+// is raw. The stdin content or file is streamed to the command on every host.
+// With a tty, stdout holds the merged output of the command. In check mode,
+// the check command runs instead unless check_mode is false.
+func (op OpShell) Execute(t remote.Transport, ctx *Context) (*gtypes.OrderedMap[string, string], error) {
+	if ctx.checking() && !op.ignoreCheck {
+		return op.runCheck(t, ctx)
+	}
+
+	cmd := op.shell
+	if !op.raw {
+		cmd = ctx.Render(op.shell)
+	}
+
+	execOpts, closeStdin, err := op.options(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeStdin()
+
+	exitStatus, stdout, stderr, err := t.ExecuteCommand(cmd, execOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -58,5 +93,6 @@ func (op OpShell) Execute(r *remote.Remote) (*gtypes.OrderedMap[string, string],
 	res.Put("ExitStatus", strconv.Itoa(exitStatus))
 	res.Put("Stdout", stdout)
 	res.Put("Stderr", stderr)
+	res.Put(ResultChanged, "true")
 	return res, nil
 }
@@ -0,0 +1,108 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package operation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/cqroot/gtypes"
+	"github.com/cqroot/minop/pkg/logs"
+	"github.com/cqroot/minop/pkg/remote"
+)
+
+// OpTemplate renders a local Go template with the host's variables and uploads
+// the result to remote hosts.
+type OpTemplate struct {
+	baseOperationImpl
+	uploadSettings
+	template string
+}
+
+// NewOpTemplate creates a new OpTemplate operation from the given Input.
+// Returns ErrInvalidOperation if the To field is empty or Mode is invalid.
+func NewOpTemplate(in Input) (*OpTemplate, error) {
+	settings, err := newUploadSettings(in)
+	if err != nil {
+		return nil, err
+	}
+	return &OpTemplate{
+		uploadSettings: settings,
+		template:       in.Template,
+	}, nil
+}
+
+// DefaultName returns the default name for template operations.
+func (op OpTemplate) DefaultName() string {
+	return fmt.Sprintf("[template] %s => %s", op.template, op.to)
+}
+
+// Execute renders the template with the variables of the context and uploads
+// the result to the remote host unless it has the same content already.
+// Templates in the source and destination paths are expanded as well. In
+// check mode, it only reports whether the remote file would change. In diff
+// mode, it reports how the remote file changes.
+func (op OpTemplate) Execute(t remote.Transport, ctx *Context) (*gtypes.OrderedMap[string, string], error) {
+	src := ctx.Render(op.template)
+	dst := ctx.Render(op.to)
+
+	text, err := os.ReadFile(src)
+	if err != nil {
+		logs.Logger().Err(err).Msg("")
+		return nil, err
+	}
+
+	rendered, err := Render(filepath.Base(src), string(text), ctx.Vars)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.CreateTemp("", "minop-template-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	_, err = f.WriteString(rendered)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	changed, d, err := op.uploadFile(t, f.Name(), dst, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := gtypes.NewOrderedMap[string, string]()
+	res.Put("Result", fmt.Sprintf("%s -> %s", src, dst))
+	if d != "" {
+		res.Put(ResultDiff, d)
+	}
+	res.Put(ResultChanged, strconv.FormatBool(changed))
+	return res, nil
+}
+
+// SupportsCheck reports that template operations support check mode.
+func (op OpTemplate) SupportsCheck() bool {
+	return true
+}
@@ -0,0 +1,131 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/cqroot/minop/pkg/logs"
+	"github.com/cqroot/minop/pkg/remote"
+	"gopkg.in/yaml.v3"
+)
+
+// templateFuncs are the helper functions available in templates.
+var templateFuncs = template.FuncMap{
+	// default returns def if val is nil, an empty string or a zero number.
+	"default": func(def, val any) any {
+		if val == nil {
+			return def
+		}
+		if rv := reflect.ValueOf(val); rv.IsZero() {
+			return def
+		}
+		return val
+	},
+	"upper":     strings.ToUpper,
+	"lower":     strings.ToLower,
+	"trim":      strings.TrimSpace,
+	"replace":   func(old, repl, s string) string { return strings.ReplaceAll(s, old, repl) },
+	"split":     func(sep, s string) []string { return strings.Split(s, sep) },
+	"join":      func(sep string, elems []string) string { return strings.Join(elems, sep) },
+	"contains":  func(substr, s string) bool { return strings.Contains(s, substr) },
+	"hasPrefix": func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix": func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+	"quote":     remote.ShellQuote,
+	"indent": func(n int, s string) string {
+		pad := strings.Repeat(" ", n)
+		return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+	},
+	"toJson": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"toYaml": func(v any) (string, error) {
+		b, err := yaml.Marshal(v)
+		return strings.TrimSuffix(string(b), "\n"), err
+	},
+}
+
+// Render executes text as a Go text/template with vars as data.
+// Referencing a missing variable is an error; use "index . "name"" together
+// with "default" for optional variables.
+func Render(name, text string, vars Vars) (string, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("parse template %s: %w", name, err)
+	}
+
+	sb := strings.Builder{}
+	if err := tmpl.Execute(&sb, map[string]any(vars)); err != nil {
+		return "", fmt.Errorf("render template %s: %w", name, err)
+	}
+	return sb.String(), nil
+}
+
+// RenderField expands the template in a task field with vars. Unlike Render,
+// it does not fail: task fields often hold the Go templates of other tools,
+// as in docker ps --format '{{.Names}}', so the top-level actions that cannot
+// be parsed or rendered, such as those referring to undefined variables, are
+// left as they are.
+func RenderField(field string, vars Vars) string {
+	if rendered, err := Render(field, field, vars); err == nil {
+		return rendered
+	}
+
+	tmpl, err := template.New(field).Funcs(templateFuncs).Parse(field)
+	if err != nil {
+		logs.Logger().Debug().Err(err).Str("field", field).Msg("field left unrendered")
+		return field
+	}
+
+	// The source between the top-level text nodes holds the actions, which
+	// are rendered one span at a time.
+	sb := strings.Builder{}
+	pos := 0
+	for _, node := range tmpl.Tree.Root.Nodes {
+		text, ok := node.(*parse.TextNode)
+		if !ok {
+			continue
+		}
+		start := int(text.Position())
+		sb.WriteString(renderSpan(field[pos:start], vars))
+		sb.Write(text.Text)
+		pos = start + len(text.Text)
+	}
+	sb.WriteString(renderSpan(field[pos:], vars))
+	return sb.String()
+}
+
+// renderSpan renders the actions in span with vars, or returns span as it is
+// if they cannot be rendered.
+func renderSpan(span string, vars Vars) string {
+	if span == "" {
+		return ""
+	}
+	rendered, err := Render(span, span, vars)
+	if err != nil {
+		logs.Logger().Debug().Err(err).Str("action", span).Msg("action left unrendered")
+		return span
+	}
+	return rendered
+}
@@ -0,0 +1,270 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package operation
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"github.com/cqroot/minop/pkg/diff"
+	"github.com/cqroot/minop/pkg/logs"
+	"github.com/cqroot/minop/pkg/remote"
+)
+
+// uploadSettings holds the destination settings shared by operations that
+// upload files: copy and template.
+type uploadSettings struct {
+	to     string
+	backup bool
+	mode   os.FileMode // 0 keeps the mode the file is created with
+}
+
+// newUploadSettings validates the destination settings of the Input.
+// Returns ErrInvalidOperation if the To field is empty or Mode is not an octal number.
+func newUploadSettings(in Input) (uploadSettings, error) {
+	if in.To == "" {
+		return uploadSettings{}, MakeErrInvalidOperation(in)
+	}
+
+	s := uploadSettings{
+		to:     in.To,
+		backup: in.Backup,
+	}
+
+	if in.Mode != "" {
+		mode, err := strconv.ParseUint(in.Mode, 8, 32)
+		if err != nil || mode > 0o7777 {
+			return uploadSettings{}, fmt.Errorf("%w: invalid mode %q", ErrInvalidOperation, in.Mode)
+		}
+		s.mode = os.FileMode(mode)
+	}
+
+	return s, nil
+}
+
+// backupDst copies dst to "<dst>.minop_bak" unless a backup exists already.
+func (s uploadSettings) backupDst(t remote.Transport, dst string) error {
+	logs.Logger().Debug().Str("Dst", dst).Msg("backup file")
+	ret, stdout, stderr, err := t.ExecuteCommand(fmt.Sprintf(
+		"if [ ! -e %[2]s ] && [ -f %[1]s ]; then cp -a -- %[1]s %[2]s; else exit 0; fi",
+		remote.ShellQuote(dst), remote.ShellQuote(dst+".minop_bak")))
+	if err != nil {
+		logs.Logger().Err(err).Msg("failed to back up source file")
+		return err
+	}
+	if ret != 0 {
+		err := fmt.Errorf("command ret: %d, out: %s, err: %s", ret, stdout, stderr)
+		logs.Logger().Err(err).Msg("failed to back up source file")
+		return err
+	}
+	return nil
+}
+
+// MaxDiffSize is the size in bytes above which files are not diffed in diff mode.
+const MaxDiffSize = 1 << 20
+
+// uploadFile uploads a local file to dst unless dst has the same content
+// already, backing up the previous dst first. The mode is applied afterwards
+// if requested and the permission bits differ. Reports whether dst changed
+// and, in diff mode, how its content changed. In check mode, it only reports
+// whether and how dst would change.
+func (s uploadSettings) uploadFile(t remote.Transport, localPath, dst string, ctx *Context) (bool, string, error) {
+	differs, err := remoteFileDiffers(t, localPath, dst)
+	if err != nil {
+		return false, "", err
+	}
+
+	var d string
+	if differs && ctx.diffing() {
+		if d, err = fileDiff(t, localPath, dst); err != nil {
+			return false, "", err
+		}
+	}
+	changed, err := s.applyFile(t, localPath, dst, differs, ctx.checking())
+	return changed, d, err
+}
+
+// applyFile uploads a local file to dst if differs is set, backing up the
+// previous dst first, and applies the mode. Reports whether dst changed. In
+// check mode, it only reports whether dst would change.
+func (s uploadSettings) applyFile(t remote.Transport, localPath, dst string, differs, check bool) (bool, error) {
+	if differs && check {
+		return true, nil
+	}
+	if differs {
+		if s.backup {
+			if err := s.backupDst(t, dst); err != nil {
+				return false, err
+			}
+		}
+
+		if err := t.UploadFile(localPath, dst); err != nil {
+			return false, err
+		}
+	}
+
+	if s.mode == 0 {
+		return differs, nil
+	}
+
+	info, err := t.Stat(dst)
+	if err != nil {
+		return differs, err
+	}
+	if !differs && info.Mode().Perm() == s.mode.Perm() {
+		return false, nil
+	}
+	if check {
+		return true, nil
+	}
+	if err := t.Chmod(dst, s.mode); err != nil {
+		logs.Logger().Err(err).Str("Dst", dst).Msg("failed to change file mode")
+		return differs, err
+	}
+	return true, nil
+}
+
+// fileDiff returns the unified diff from the content of dst on the remote
+// host, empty if it is missing, to the content of the local file. Files
+// larger than MaxDiffSize and files that are not text are only reported as
+// differing.
+func fileDiff(t remote.Transport, localPath, dst string) (string, error) {
+	local, err := os.ReadFile(localPath)
+	if err != nil {
+		return "", err
+	}
+
+	fromName := dst
+	var content []byte
+	info, err := t.Stat(dst)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		fromName = "/dev/null"
+	case err != nil:
+		return "", err
+	case info.IsDir():
+		return fmt.Sprintf("%s is a directory\n", dst), nil
+	case info.Size() > MaxDiffSize:
+		return fmt.Sprintf("%s is too large to diff (%d bytes)\n", dst, info.Size()), nil
+	default:
+		if content, err = readRemoteFile(t, dst); err != nil {
+			return "", err
+		}
+	}
+
+	if len(local) > MaxDiffSize {
+		return fmt.Sprintf("%s: new content is too large to diff (%d bytes)\n", dst, len(local)), nil
+	}
+	if !diff.IsText(content) || !diff.IsText(local) {
+		return fmt.Sprintf("Binary file %s differs\n", dst), nil
+	}
+	return diff.Unified(fromName, dst, string(content), string(local)), nil
+}
+
+// readRemoteFile returns the content of a file on the remote host.
+func readRemoteFile(t remote.Transport, remotePath string) ([]byte, error) {
+	f, err := os.CreateTemp("", "minop-download-*")
+	if err != nil {
+		return nil, err
+	}
+	_ = f.Close()
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	if err := t.DownloadFile(remotePath, f.Name()); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(f.Name())
+}
+
+// remoteFileDiffers reports whether dst is missing on the remote host or has
+// other content than the local file. Files of the same size are compared by
+// checksum, without downloading dst; if the checksum of dst cannot be computed
+// on the host, it is reported as differing.
+func remoteFileDiffers(t remote.Transport, localPath, dst string) (bool, error) {
+	info, err := t.Stat(dst)
+	if errors.Is(err, os.ErrNotExist) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if info.IsDir() {
+		return true, nil
+	}
+
+	local, err := os.Stat(localPath)
+	if err != nil {
+		return false, err
+	}
+	if local.Size() != info.Size() {
+		return true, nil
+	}
+
+	localSum, err := remote.FileChecksum(localPath)
+	if err != nil {
+		return false, err
+	}
+	remoteSum, err := t.Checksum(dst)
+	if err != nil {
+		// Hosts without a checksum tool get the file uploaded again.
+		logs.Logger().Debug().Err(err).Str("Dst", dst).Msg("failed to checksum remote file, assuming it differs")
+		return true, nil
+	}
+	return localSum != remoteSum, nil
+}
+
+// errDiffers stops the walk of remoteDirDiffers at the first difference.
+var errDiffers = errors.New("differs")
+
+// walkRemoteDiffs calls fn with the local and remote paths of each file below
+// the local directory that is missing below dst on the remote host or has
+// other content.
+func walkRemoteDiffs(t remote.Transport, localDir, dst string, fn func(localPath, remotePath string) error) error {
+	return filepath.WalkDir(localDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(localDir, p)
+		if err != nil {
+			return err
+		}
+		remotePath := path.Join(remote.ToUnixPath(dst), filepath.ToSlash(rel))
+		differs, err := remoteFileDiffers(t, p, remotePath)
+		if err == nil && differs {
+			return fn(p, remotePath)
+		}
+		return err
+	})
+}
+
+// remoteDirDiffers reports whether any file below the local directory is
+// missing below dst on the remote host or has other content.
+func remoteDirDiffers(t remote.Transport, localDir, dst string) (bool, error) {
+	err := walkRemoteDiffs(t, localDir, dst, func(string, string) error {
+		return errDiffers
+	})
+	if errors.Is(err, errDiffers) {
+		return true, nil
+	}
+	return false, err
+}
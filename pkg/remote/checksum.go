@@ -0,0 +1,60 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package remote
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// FileChecksum returns the hex encoded SHA-256 checksum of a local file, in
+// the form returned by Transport.Checksum.
+func FileChecksum(localPath string) (string, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checksumCommand returns the command that prints the SHA-256 checksum of
+// remotePath with "sha256sum", falling back to "shasum -a 256".
+func checksumCommand(remotePath string) string {
+	quoted := ShellQuote(remotePath)
+	return fmt.Sprintf("sha256sum -- %[1]s 2>/dev/null || shasum -a 256 -- %[1]s", quoted)
+}
+
+// parseChecksum returns the checksum in the output of "sha256sum" or
+// "shasum", which is followed by the file name.
+func parseChecksum(stdout string) (string, error) {
+	sum, _, _ := strings.Cut(strings.TrimSpace(stdout), " ")
+	if _, err := hex.DecodeString(sum); err != nil || len(sum) != sha256.Size*2 {
+		return "", fmt.Errorf("invalid sha256sum output %q", stdout)
+	}
+	return strings.ToLower(sum), nil
+}
@@ -0,0 +1,189 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ExecOption configures a single command execution.
+type ExecOption func(c *execConfig)
+
+// execConfig holds the settings of a single command execution.
+type execConfig struct {
+	pty     *PtyConfig
+	env     map[string]string
+	dir     string
+	stdin   io.Reader
+	timeout time.Duration
+	become  bool
+}
+
+// newExecConfig applies opts to an empty execConfig.
+func newExecConfig(opts []ExecOption) execConfig {
+	c := execConfig{}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// WithEnv sets environment variables for the command. Remote hosts receive them
+// through SSH "env" requests; if the server refuses any of them (see AcceptEnv in
+// sshd_config), they are exported in a prefix of the command instead.
+// Names must be valid environment variable names, see ValidateEnvName.
+func WithEnv(env map[string]string) ExecOption {
+	return func(c *execConfig) {
+		if c.env == nil {
+			c.env = make(map[string]string, len(env))
+		}
+		for k, v := range env {
+			c.env[k] = v
+		}
+	}
+}
+
+// WithDir runs the command in the given working directory.
+// The command fails with the status of "cd" if the directory cannot be entered.
+func WithDir(dir string) ExecOption {
+	return func(c *execConfig) {
+		c.dir = dir
+	}
+}
+
+// WithStdin streams the content of stdin to the standard input of the command.
+// The reader is consumed, so every command needs its own.
+func WithStdin(stdin io.Reader) ExecOption {
+	return func(c *execConfig) {
+		c.stdin = stdin
+	}
+}
+
+// WithBecome runs the command as root through "sudo -n", which fails instead
+// of prompting if sudo needs a password. Since sudo resets the environment,
+// the variables of WithEnv are exported in a prefix of the command.
+func WithBecome() ExecOption {
+	return func(c *execConfig) {
+		c.become = true
+	}
+}
+
+// ErrCommandTimeout is returned when a command runs longer than its timeout.
+var ErrCommandTimeout = errors.New("command timed out")
+
+// WithTimeout stops the command and returns ErrCommandTimeout if it runs
+// longer than timeout. A value of 0 or negative disables the timeout.
+func WithTimeout(timeout time.Duration) ExecOption {
+	return func(c *execConfig) {
+		c.timeout = timeout
+	}
+}
+
+// Default pseudo terminal settings.
+const (
+	DefaultPtyTerm   = "xterm"
+	DefaultPtyWidth  = 200
+	DefaultPtyHeight = 50
+)
+
+// PtyConfig describes the pseudo terminal allocated for a command.
+// Zero fields are replaced by the defaults.
+type PtyConfig struct {
+	Term   string
+	Width  int
+	Height int
+	Modes  ssh.TerminalModes
+}
+
+// WithPty allocates a pseudo terminal for the command. The remote side merges
+// stderr into stdout, so all output is returned as stdout.
+func WithPty(cfg PtyConfig) ExecOption {
+	return func(c *execConfig) {
+		if cfg.Term == "" {
+			cfg.Term = DefaultPtyTerm
+		}
+		if cfg.Width <= 0 {
+			cfg.Width = DefaultPtyWidth
+		}
+		if cfg.Height <= 0 {
+			cfg.Height = DefaultPtyHeight
+		}
+
+		modes := ssh.TerminalModes{
+			ssh.ECHO:          0,
+			ssh.TTY_OP_ISPEED: 14400,
+			ssh.TTY_OP_OSPEED: 14400,
+		}
+		for k, v := range cfg.Modes {
+			modes[k] = v
+		}
+		cfg.Modes = modes
+
+		c.pty = &cfg
+	}
+}
+
+// terminalModeNames maps the names accepted by ParseTerminalModes to their opcodes.
+var terminalModeNames = map[string]uint8{
+	"VINTR":         ssh.VINTR,
+	"VQUIT":         ssh.VQUIT,
+	"VERASE":        ssh.VERASE,
+	"VKILL":         ssh.VKILL,
+	"VEOF":          ssh.VEOF,
+	"IGNCR":         ssh.IGNCR,
+	"ICRNL":         ssh.ICRNL,
+	"INLCR":         ssh.INLCR,
+	"IXON":          ssh.IXON,
+	"IXOFF":         ssh.IXOFF,
+	"IUTF8":         ssh.IUTF8,
+	"ISIG":          ssh.ISIG,
+	"ICANON":        ssh.ICANON,
+	"ECHO":          ssh.ECHO,
+	"ECHOE":         ssh.ECHOE,
+	"ECHOK":         ssh.ECHOK,
+	"ECHONL":        ssh.ECHONL,
+	"ECHOCTL":       ssh.ECHOCTL,
+	"IEXTEN":        ssh.IEXTEN,
+	"OPOST":         ssh.OPOST,
+	"ONLCR":         ssh.ONLCR,
+	"OCRNL":         ssh.OCRNL,
+	"CS7":           ssh.CS7,
+	"CS8":           ssh.CS8,
+	"TTY_OP_ISPEED": ssh.TTY_OP_ISPEED,
+	"TTY_OP_OSPEED": ssh.TTY_OP_OSPEED,
+}
+
+// ParseTerminalModes converts a map of mode names such as "ECHO" or "ICANON"
+// (case-insensitive) to ssh.TerminalModes.
+func ParseTerminalModes(modes map[string]uint32) (ssh.TerminalModes, error) {
+	ret := make(ssh.TerminalModes, len(modes))
+	for name, val := range modes {
+		opcode, ok := terminalModeNames[strings.ToUpper(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown terminal mode: %s", name)
+		}
+		ret[opcode] = val
+	}
+	return ret, nil
+}
@@ -0,0 +1,182 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package remote
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CommandHandler computes the exit status, stdout and stderr of a command run on a Fake.
+type CommandHandler func(cmd string) (int, string, string, error)
+
+// Fake is an in-memory Transport for tests. Uploaded files are kept in Files,
+// modes set with Chmod in Modes, and every executed command is recorded in Commands.
+type Fake struct {
+	mu       sync.Mutex
+	Files    map[string][]byte
+	Modes    map[string]os.FileMode
+	Commands []string
+	Handler  CommandHandler
+}
+
+// NewFake creates a Fake whose commands are answered by handler.
+// A nil handler makes every command succeed with empty output.
+func NewFake(handler CommandHandler) *Fake {
+	return &Fake{
+		Files:   make(map[string][]byte),
+		Modes:   make(map[string]os.FileMode),
+		Handler: handler,
+	}
+}
+
+// Close is a no-op for fake transports.
+func (f *Fake) Close() error {
+	return nil
+}
+
+// ExecuteCommand records cmd and returns the result of the Handler.
+// Execution options are ignored.
+func (f *Fake) ExecuteCommand(cmd string, opts ...ExecOption) (int, string, string, error) {
+	f.mu.Lock()
+	f.Commands = append(f.Commands, cmd)
+	handler := f.Handler
+	f.mu.Unlock()
+
+	if handler == nil {
+		return 0, "", "", nil
+	}
+	return handler(cmd)
+}
+
+// UploadFile stores the content of a local file at remotePath.
+func (f *Fake) UploadFile(localPath, remotePath string) error {
+	content, err := os.ReadFile(localPath)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Files[ToUnixPath(remotePath)] = content
+	return nil
+}
+
+// UploadDir stores every file below localDir under remoteDir.
+func (f *Fake) UploadDir(localDir, remoteDir string) error {
+	return filepath.Walk(localDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		relPath, err := filepath.Rel(localDir, p)
+		if err != nil {
+			return err
+		}
+		return f.UploadFile(p, path.Join(ToUnixPath(remoteDir), filepath.ToSlash(relPath)))
+	})
+}
+
+// DownloadFile writes the content stored at remotePath to a local file.
+func (f *Fake) DownloadFile(remotePath, localPath string) error {
+	f.mu.Lock()
+	content, ok := f.Files[ToUnixPath(remotePath)]
+	f.mu.Unlock()
+	if !ok {
+		return &os.PathError{Op: "open", Path: remotePath, Err: os.ErrNotExist}
+	}
+
+	return os.WriteFile(localPath, content, 0o644)
+}
+
+// Checksum returns the SHA-256 checksum of the content stored at remotePath.
+func (f *Fake) Checksum(remotePath string) (string, error) {
+	f.mu.Lock()
+	content, ok := f.Files[ToUnixPath(remotePath)]
+	f.mu.Unlock()
+	if !ok {
+		return "", &os.PathError{Op: "open", Path: remotePath, Err: os.ErrNotExist}
+	}
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Stat describes remotePath as a file if it was uploaded, or as a directory
+// if any uploaded file lies below it.
+func (f *Fake) Stat(remotePath string) (os.FileInfo, error) {
+	p := ToUnixPath(remotePath)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if content, ok := f.Files[p]; ok {
+		mode, ok := f.Modes[p]
+		if !ok {
+			mode = 0o644
+		}
+		return fakeFileInfo{name: path.Base(p), size: int64(len(content)), mode: mode}, nil
+	}
+	for name := range f.Files {
+		if strings.HasPrefix(name, strings.TrimSuffix(p, "/")+"/") {
+			return fakeFileInfo{name: path.Base(p), dir: true}, nil
+		}
+	}
+	return nil, &os.PathError{Op: "stat", Path: remotePath, Err: os.ErrNotExist}
+}
+
+// Chmod records the mode of an uploaded file.
+func (f *Fake) Chmod(remotePath string, mode os.FileMode) error {
+	p := ToUnixPath(remotePath)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.Files[p]; !ok {
+		return &os.PathError{Op: "chmod", Path: remotePath, Err: os.ErrNotExist}
+	}
+	f.Modes[p] = mode
+	return nil
+}
+
+// fakeFileInfo implements os.FileInfo for entries of a Fake.
+type fakeFileInfo struct {
+	name string
+	size int64
+	mode os.FileMode
+	dir  bool
+}
+
+func (fi fakeFileInfo) Name() string       { return fi.name }
+func (fi fakeFileInfo) Size() int64        { return fi.size }
+func (fi fakeFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi fakeFileInfo) IsDir() bool        { return fi.dir }
+func (fi fakeFileInfo) Sys() any           { return nil }
+
+func (fi fakeFileInfo) Mode() os.FileMode {
+	if fi.dir {
+		return os.ModeDir | 0o755
+	}
+	return fi.mode
+}
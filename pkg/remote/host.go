@@ -36,6 +36,28 @@ type Host struct {
 	Port     int
 }
 
+// LocalHost is the host line and address of the control machine.
+// Tasks on it run through a Local transport instead of SSH.
+const LocalHost = "local"
+
+// NewLocalHost returns the Host that represents the control machine.
+func NewLocalHost() Host {
+	return Host{Address: LocalHost}
+}
+
+// IsLocal reports whether the host is the control machine.
+func (h Host) IsLocal() bool {
+	return h.User == "" && h.Address == LocalHost
+}
+
+// String returns the host in the form "<user>@<address>:<port>", or "local".
+func (h Host) String() string {
+	if h.IsLocal() {
+		return LocalHost
+	}
+	return fmt.Sprintf("%s@%s:%d", h.User, h.Address, h.Port)
+}
+
 // Host parsing errors
 var (
 	ErrEmptyUsername      = errors.New("empty username")
@@ -47,8 +69,12 @@ var (
 
 // ParseHostLine parses a host connection string in the format "<user>:<password>@<address>:<port>".
 // Supports IPv6 addresses in brackets, e.g., "user:pass@[::1]:22".
-// Defaults port to 22 if not specified.
+// Defaults port to 22 if not specified. The line "local" yields the control machine.
 func ParseHostLine(line string) (Host, error) {
+	if line == LocalHost {
+		return NewLocalHost(), nil
+	}
+
 	h := Host{}
 	s := line
 
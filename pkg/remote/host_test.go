@@ -45,6 +45,12 @@ func TestParseHostLine(t *testing.T) {
 			},
 			err: nil,
 		},
+		{
+			name:     "local host",
+			line:     "local",
+			expected: remote.NewLocalHost(),
+			err:      nil,
+		},
 		{
 			name:     "empty username",
 			line:     ":password@hostname:22",
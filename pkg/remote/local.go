@@ -0,0 +1,180 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package remote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/cqroot/minop/pkg/logs"
+	"github.com/rs/zerolog"
+)
+
+// Local is a Transport that runs commands and copies files on the control machine.
+type Local struct {
+	Logger zerolog.Logger
+}
+
+// NewLocal creates a new Local transport.
+func NewLocal() *Local {
+	return &Local{
+		Logger: logs.Logger().With().Str("host", "local").Logger(),
+	}
+}
+
+// Close is a no-op for local transports.
+func (l *Local) Close() error {
+	return nil
+}
+
+// ExecuteCommand runs the command with "sh -c" on the control machine, through
+// "sudo -n" for WithBecome.
+// No terminal is allocated for WithPty, but stderr is merged into stdout as it
+// would be on a terminal.
+func (l *Local) ExecuteCommand(cmd string, opts ...ExecOption) (int, string, string, error) {
+	cfg := newExecConfig(opts)
+
+	var (
+		exitStatus = 0
+		stdout     bytes.Buffer
+		stderr     bytes.Buffer
+	)
+
+	ctx := context.Background()
+	if cfg.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.timeout)
+		defer cancel()
+	}
+
+	c := exec.CommandContext(ctx, "sh", "-c", cmd)
+	if cfg.become {
+		// sudo resets the environment, so variables are exported in the command.
+		c = exec.CommandContext(ctx, "sudo", "-n", "--", "sh", "-c", commandPrefix(cfg.env, "")+cmd)
+	}
+	// Do not wait forever for background processes that keep the output open.
+	c.WaitDelay = time.Second
+	c.Dir = cfg.dir
+	if len(cfg.env) > 0 && !cfg.become {
+		c.Env = os.Environ()
+		for _, k := range sortedKeys(cfg.env) {
+			c.Env = append(c.Env, k+"="+cfg.env[k])
+		}
+	}
+	c.Stdin = cfg.stdin
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	if cfg.pty != nil {
+		c.Stderr = &stdout
+	}
+
+	err := c.Run()
+	var e *exec.ExitError
+	if ctx.Err() != nil {
+		err = fmt.Errorf("%w after %s", ErrCommandTimeout, cfg.timeout)
+		l.Logger.Error().Err(err).Msg("command execution error")
+		return 0, stdout.String(), stderr.String(), err
+	} else if err != nil && errors.As(err, &e) {
+		exitStatus = e.ExitCode()
+	} else if err != nil {
+		l.Logger.Error().Err(err).Msg("command execution error")
+		return 0, "", "", fmt.Errorf("command execution error: %w", err)
+	}
+
+	return exitStatus, stdout.String(), stderr.String(), nil
+}
+
+// copyLocalFile copies src to dst, creating the parent directories of dst.
+func copyLocalFile(src, dst string) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("open source file error: %w", err)
+	}
+	defer func() { _ = srcFile.Close() }()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("create destination directory error: %w", err)
+	}
+
+	dstFile, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("create destination file error: %w", err)
+	}
+	defer func() { _ = dstFile.Close() }()
+
+	if _, err := io.Copy(dstFile, srcFile); err != nil {
+		return fmt.Errorf("copy file content error: %w", err)
+	}
+	return nil
+}
+
+// UploadFile copies a local file to another local path.
+func (l *Local) UploadFile(localPath, remotePath string) error {
+	if err := copyLocalFile(localPath, remotePath); err != nil {
+		l.Logger.Error().Err(err).Str("local", localPath).Str("remote", remotePath).Msg("file copy error")
+		return err
+	}
+	return nil
+}
+
+// UploadDir copies a local directory recursively to another local path.
+func (l *Local) UploadDir(localDir, remoteDir string) error {
+	return filepath.Walk(localDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(localDir, path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(remoteDir, relPath)
+
+		if info.IsDir() {
+			return os.MkdirAll(dst, 0o755)
+		}
+		return l.UploadFile(path, dst)
+	})
+}
+
+// DownloadFile copies a local file to another local path.
+func (l *Local) DownloadFile(remotePath, localPath string) error {
+	return l.UploadFile(remotePath, localPath)
+}
+
+// Checksum returns the SHA-256 checksum of a local file.
+func (l *Local) Checksum(remotePath string) (string, error) {
+	return FileChecksum(remotePath)
+}
+
+// Stat returns the file info of a local path.
+func (l *Local) Stat(remotePath string) (os.FileInfo, error) {
+	return os.Stat(remotePath)
+}
+
+// Chmod changes the permission bits of a local path.
+func (l *Local) Chmod(remotePath string, mode os.FileMode) error {
+	return os.Chmod(remotePath, mode)
+}
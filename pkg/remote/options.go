@@ -0,0 +1,37 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package remote
+
+// DefaultMaxSessions is the default number of concurrent SSH sessions per Remote.
+// sshd limits a connection to MaxSessions (10 by default) open channels, and the
+// SFTP subsystem keeps one of them for the lifetime of the connection.
+const DefaultMaxSessions = 9
+
+// Option configures a Remote.
+type Option func(r *Remote)
+
+// WithMaxSessions sets the maximum number of SSH sessions a Remote opens at once.
+// Callers beyond the limit wait for a session to be released instead of failing.
+// A value of 0 or negative is ignored and DefaultMaxSessions is used.
+func WithMaxSessions(maxSessions int) Option {
+	return func(r *Remote) {
+		if maxSessions > 0 {
+			r.maxSessions = maxSessions
+		}
+	}
+}
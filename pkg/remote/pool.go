@@ -17,31 +17,99 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 
 package remote
 
-// HostPool manages a cache of Remote connections keyed by Host.
+import (
+	"fmt"
+	"sync"
+)
+
+// HostPool manages a cache of Transport connections keyed by Host.
 // It reuses existing connections to avoid redundant SSH/SFTP handshakes.
+// It is safe for concurrent use; connections to different hosts are opened
+// in parallel.
 type HostPool struct {
-	hosts map[Host]*Remote
+	mu    sync.Mutex
+	hosts map[Host]*poolEntry
+	dial  Dialer
+}
+
+// poolEntry is the connection to one host. ready is closed once the dial
+// that opens it has finished and t and err are set.
+type poolEntry struct {
+	ready chan struct{}
+	t     Transport
+	err   error
+}
+
+// NewHostPool creates a new empty HostPool that connects to hosts over SSH,
+// or through a Local transport for the control machine.
+// The given options are applied to every Remote created by the pool.
+func NewHostPool(opts ...Option) *HostPool {
+	return NewHostPoolWithDialer(func(h Host) (Transport, error) {
+		if h.IsLocal() {
+			return NewLocal(), nil
+		}
+		return New(h, opts...)
+	})
 }
 
-// NewHostPool creates a new empty HostPool.
-func NewHostPool() *HostPool {
+// NewHostPoolWithDialer creates a new empty HostPool that opens connections with dial.
+func NewHostPoolWithDialer(dial Dialer) *HostPool {
 	return &HostPool{
-		hosts: make(map[Host]*Remote),
+		hosts: make(map[Host]*poolEntry),
+		dial:  dial,
 	}
 }
 
-// GetRemote returns a Remote connection for the given Host.
+// GetTransport returns a Transport connection for the given Host.
 // If a connection already exists in the pool, it returns the cached one.
-// Otherwise, it creates a new connection and caches it.
-func (p *HostPool) GetRemote(host Host) (*Remote, error) {
-	r, ok := p.hosts[host]
-	if !ok {
-		newR, err := New(host)
-		if err != nil {
-			return nil, err
+// Otherwise, it creates a new connection and caches it; concurrent calls
+// for the same host wait for that connection. Failed connections are not
+// cached, so a later call dials again.
+func (p *HostPool) GetTransport(host Host) (Transport, error) {
+	p.mu.Lock()
+	e, ok := p.hosts[host]
+	if ok {
+		p.mu.Unlock()
+		<-e.ready
+		return e.t, e.err
+	}
+	e = &poolEntry{ready: make(chan struct{})}
+	p.hosts[host] = e
+	p.mu.Unlock()
+
+	e.t, e.err = p.dial(host)
+	if e.err != nil {
+		p.mu.Lock()
+		if p.hosts[host] == e {
+			delete(p.hosts, host)
 		}
-		p.hosts[host] = newR
-		r = newR
+		p.mu.Unlock()
+	}
+	close(e.ready)
+	return e.t, e.err
+}
+
+// Close closes every connection in the pool and empties it. Connections
+// that are still being opened are closed once they are established.
+func (p *HostPool) Close() error {
+	p.mu.Lock()
+	hosts := p.hosts
+	p.hosts = make(map[Host]*poolEntry)
+	p.mu.Unlock()
+
+	var errs []error
+	for _, e := range hosts {
+		<-e.ready
+		if e.err != nil {
+			continue
+		}
+		if err := e.t.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("multiple errors closing connections: %v", errs)
 	}
-	return r, nil
+	return nil
 }
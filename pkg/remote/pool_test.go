@@ -0,0 +1,91 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package remote_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cqroot/minop/pkg/remote"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostPoolGetTransport(t *testing.T) {
+	a := remote.Host{User: "root", Address: "a", Port: 22}
+	b := remote.Host{User: "root", Address: "b", Port: 22}
+
+	var dials atomic.Int32
+	bDialing := make(chan struct{})
+	pool := remote.NewHostPoolWithDialer(func(h remote.Host) (remote.Transport, error) {
+		dials.Add(1)
+		switch h {
+		case a:
+			// The dial to a only finishes once b is being dialed, which
+			// needs the two handshakes to run in parallel.
+			select {
+			case <-bDialing:
+			case <-time.After(5 * time.Second):
+				return nil, errors.New("dials are serialized")
+			}
+		case b:
+			close(bDialing)
+		}
+		return remote.NewFake(nil), nil
+	})
+
+	var wg sync.WaitGroup
+	transports := make([]remote.Transport, 4)
+	for i, h := range []remote.Host{a, a, b, a} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tr, err := pool.GetTransport(h)
+			require.Nil(t, err)
+			transports[i] = tr
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, int32(2), dials.Load())
+	require.Same(t, transports[0], transports[1])
+	require.Same(t, transports[0], transports[3])
+	require.NotSame(t, transports[0], transports[2])
+	require.Nil(t, pool.Close())
+}
+
+func TestHostPoolGetTransportRetry(t *testing.T) {
+	h := remote.Host{User: "root", Address: "a", Port: 22}
+
+	var dials atomic.Int32
+	pool := remote.NewHostPoolWithDialer(func(remote.Host) (remote.Transport, error) {
+		if dials.Add(1) == 1 {
+			return nil, errors.New("connection refused")
+		}
+		return remote.NewFake(nil), nil
+	})
+
+	_, err := pool.GetTransport(h)
+	require.ErrorContains(t, err, "connection refused")
+
+	_, err = pool.GetTransport(h)
+	require.Nil(t, err)
+	require.Equal(t, int32(2), dials.Load())
+}
@@ -19,18 +19,21 @@ package remote
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/cqroot/minop/pkg/logs"
 	"github.com/pkg/sftp"
 	"github.com/rs/zerolog"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/sync/semaphore"
 )
 
 // Remote represents a SSH/SFTP client for remote server operations
@@ -42,17 +45,26 @@ type Remote struct {
 	Logger   zerolog.Logger
 	client   *ssh.Client  // SSH client
 	sftp     *sftp.Client // SFTP client
+
+	maxSessions int                 // Maximum number of concurrent SSH sessions
+	sessions    *semaphore.Weighted // Limits concurrent SSH sessions to maxSessions
 }
 
 // New creates a new Remote instance and establishes connections
-func New(h Host) (*Remote, error) {
+func New(h Host, opts ...Option) (*Remote, error) {
 	r := &Remote{
-		Hostname: h.Address,
-		Port:     h.Port,
-		Username: h.User,
-		Password: h.Password,
-		Logger:   logs.Logger().With().Str("host", fmt.Sprintf("%s@%s:%d", h.User, h.Address, h.Port)).Logger(),
+		Hostname:    h.Address,
+		Port:        h.Port,
+		Username:    h.User,
+		Password:    h.Password,
+		Logger:      logs.Logger().With().Str("host", h.String()).Logger(),
+		maxSessions: DefaultMaxSessions,
+	}
+
+	for _, opt := range opts {
+		opt(r)
 	}
+	r.sessions = semaphore.NewWeighted(int64(r.maxSessions))
 
 	// Establish SSH connection
 	sshConfig := &ssh.ClientConfig{
@@ -114,24 +126,77 @@ func (r *Remote) Close() error {
 	return nil
 }
 
-// ExecuteCommand executes a command on the remote host via SSH.
-func (r *Remote) ExecuteCommand(cmd string) (int, string, string, error) {
+// newSession opens a new SSH session, waiting while maxSessions sessions are
+// already open. The returned release function closes the session and frees its slot.
+func (r *Remote) newSession() (*ssh.Session, func(), error) {
+	if err := r.sessions.Acquire(context.Background(), 1); err != nil {
+		return nil, nil, err
+	}
+
 	session, err := r.client.NewSession()
+	if err != nil {
+		r.sessions.Release(1)
+		return nil, nil, err
+	}
+
+	release := func() {
+		_ = session.Close()
+		r.sessions.Release(1)
+	}
+	return session, release, nil
+}
+
+// ExecuteCommand executes a command on the remote host via SSH.
+// If maxSessions commands are already running on this Remote, it waits for one
+// of them to finish.
+func (r *Remote) ExecuteCommand(cmd string, opts ...ExecOption) (int, string, string, error) {
+	cfg := newExecConfig(opts)
+
+	session, release, err := r.newSession()
 	if err != nil {
 		r.Logger.Error().Err(err).Msg("create session error")
 		return 0, "", "", fmt.Errorf("create session error: %w", err)
 	}
-	defer func() { _ = session.Close() }()
+	defer release()
 
 	var (
 		exitStatus = 0
 		stdout     bytes.Buffer
 		stderr     bytes.Buffer
 	)
+	session.Stdin = cfg.stdin
 	session.Stdout = &stdout
 	session.Stderr = &stderr
 
-	err = session.Run(cmd)
+	// sudo resets the environment, so variables are exported in the command then.
+	exportEnv := cfg.become
+	for _, k := range sortedKeys(cfg.env) {
+		if exportEnv {
+			break
+		}
+		if err := session.Setenv(k, cfg.env[k]); err != nil {
+			r.Logger.Debug().Err(err).Str("name", k).Msg("setenv refused, exporting variables in the command")
+			exportEnv = true
+		}
+	}
+	if exportEnv {
+		cmd = commandPrefix(cfg.env, cfg.dir) + cmd
+	} else {
+		cmd = commandPrefix(nil, cfg.dir) + cmd
+	}
+	if cfg.become {
+		cmd = becomeCommand(cmd)
+	}
+
+	if cfg.pty != nil {
+		err = session.RequestPty(cfg.pty.Term, cfg.pty.Height, cfg.pty.Width, cfg.pty.Modes)
+		if err != nil {
+			r.Logger.Error().Err(err).Msg("request pty error")
+			return 0, "", "", fmt.Errorf("request pty error: %w", err)
+		}
+	}
+
+	err = r.runSession(session, cmd, cfg.timeout)
 	var e *ssh.ExitError
 	if err != nil && errors.As(err, &e) {
 		exitStatus = e.ExitStatus()
@@ -139,12 +204,44 @@ func (r *Remote) ExecuteCommand(cmd string) (int, string, string, error) {
 	} else if err != nil {
 		// Other types of errors (connection issues, etc.)
 		r.Logger.Error().Err(err).Msg("command execution error")
-		return 0, "", "", fmt.Errorf("command execution error: %w", err)
+		return 0, stdout.String(), stderr.String(), fmt.Errorf("command execution error: %w", err)
 	}
 
+	if cfg.pty != nil {
+		// The terminal translates "\n" to "\r\n" on output.
+		return exitStatus, strings.ReplaceAll(stdout.String(), "\r\n", "\n"), stderr.String(), err
+	}
 	return exitStatus, stdout.String(), stderr.String(), err
 }
 
+// runSession runs cmd in the session. If it does not finish within timeout, the
+// command is killed and ErrCommandTimeout is returned.
+func (r *Remote) runSession(session *ssh.Session, cmd string, timeout time.Duration) error {
+	if timeout <= 0 {
+		return session.Run(cmd)
+	}
+
+	if err := session.Start(cmd); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- session.Wait() }()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		_ = session.Signal(ssh.SIGKILL)
+		_ = session.Close()
+		<-done
+		return fmt.Errorf("%w after %s", ErrCommandTimeout, timeout)
+	}
+}
+
 // optimalBufferSize calculates optimal buffer size based on file size
 func optimalBufferSize(fileSize int64) int {
 	// For small files (< 1MB), use 32KB buffer
@@ -223,6 +320,61 @@ func (r *Remote) UploadFile(localPath, remotePath string) error {
 	return nil
 }
 
+// DownloadFile downloads a remote file to a local path
+func (r *Remote) DownloadFile(remotePath, localPath string) error {
+	remotePath = ToUnixPath(remotePath)
+
+	remoteFile, err := r.sftp.Open(remotePath)
+	if err != nil {
+		r.Logger.Error().Err(err).Msg("open remote file error")
+		return fmt.Errorf("open remote file error: %w", err)
+	}
+	defer func() { _ = remoteFile.Close() }()
+
+	localFile, err := os.Create(localPath)
+	if err != nil {
+		r.Logger.Error().Err(err).Msg("create local file error")
+		return fmt.Errorf("create local file error: %w", err)
+	}
+	defer func() { _ = localFile.Close() }()
+
+	if _, err := remoteFile.WriteTo(localFile); err != nil {
+		r.Logger.Error().Err(err).Msg("copy file content error")
+		return fmt.Errorf("copy file content error: %w", err)
+	}
+
+	r.Logger.Info().Str("remote", remotePath).Str("local", localPath).Msg("file downloaded successfully")
+	return nil
+}
+
+// Checksum runs "sha256sum" on the remote host, or "shasum -a 256" on hosts
+// without coreutils such as BSD and macOS, to compute the SHA-256 checksum of
+// a remote file.
+func (r *Remote) Checksum(remotePath string) (string, error) {
+	remotePath = ToUnixPath(remotePath)
+
+	ret, stdout, stderr, err := r.ExecuteCommand(checksumCommand(remotePath))
+	if err != nil {
+		return "", err
+	}
+	if ret != 0 {
+		err := fmt.Errorf("checksum %s: command ret: %d, err: %s", remotePath, ret, stderr)
+		r.Logger.Error().Err(err).Msg("checksum remote file error")
+		return "", err
+	}
+	return parseChecksum(stdout)
+}
+
+// Stat returns the file info of a remote path
+func (r *Remote) Stat(remotePath string) (os.FileInfo, error) {
+	return r.sftp.Stat(ToUnixPath(remotePath))
+}
+
+// Chmod changes the permission bits of a remote path
+func (r *Remote) Chmod(remotePath string, mode os.FileMode) error {
+	return r.sftp.Chmod(ToUnixPath(remotePath), mode)
+}
+
 // ensureRemoteDir ensures that the remote directory exists, creating it if necessary
 func (r *Remote) ensureRemoteDir(remoteDir string) error {
 	// Skip if directory is empty (root)
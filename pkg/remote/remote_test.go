@@ -0,0 +1,258 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package remote_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cqroot/minop/pkg/remote"
+	"github.com/cqroot/minop/pkg/sshtest"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRemote(t *testing.T, handler sshtest.Handler, opts ...remote.Option) (*sshtest.Server, *remote.Remote) {
+	s := sshtest.NewServer(t, handler)
+	r, err := remote.New(s.Host(), opts...)
+	require.Nil(t, err)
+	t.Cleanup(func() { _ = r.Close() })
+	return s, r
+}
+
+func TestRemoteExecuteCommand(t *testing.T) {
+	_, r := newTestRemote(t, func(c *sshtest.Command) int {
+		_, _ = io.WriteString(c.Stdout, "out:"+c.Cmd)
+		_, _ = io.WriteString(c.Stderr, "err:"+c.Cmd)
+		return 2
+	})
+
+	exitStatus, stdout, stderr, err := r.ExecuteCommand("uptime")
+	require.Nil(t, err)
+	require.Equal(t, 2, exitStatus)
+	require.Equal(t, "out:uptime", stdout)
+	require.Equal(t, "err:uptime", stderr)
+}
+
+func TestRemoteMaxSessions(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	_, r := newTestRemote(t, func(c *sshtest.Command) int {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return 0
+	}, remote.WithMaxSessions(2))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, _, err := r.ExecuteCommand("sleep")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.Nil(t, err)
+	}
+	require.Equal(t, int32(2), maxRunning.Load())
+}
+
+func TestRemoteDefaultMaxSessions(t *testing.T) {
+	for _, maxSessions := range []int{0, -1} {
+		var running, maxRunning atomic.Int32
+		_, r := newTestRemote(t, func(c *sshtest.Command) int {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				m := maxRunning.Load()
+				if n <= m || maxRunning.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			// Hold the session until the limit is reached, so that callers
+			// beyond it have to wait.
+			deadline := time.Now().Add(5 * time.Second)
+			for maxRunning.Load() < remote.DefaultMaxSessions && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			return 0
+		}, remote.WithMaxSessions(maxSessions))
+
+		var wg sync.WaitGroup
+		errs := make(chan error, remote.DefaultMaxSessions+3)
+		for range remote.DefaultMaxSessions + 3 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _, _, err := r.ExecuteCommand("sleep")
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			require.Nil(t, err)
+		}
+		require.Equal(t, int32(remote.DefaultMaxSessions), maxRunning.Load(), maxSessions)
+	}
+}
+
+func TestRemoteFileTransfer(t *testing.T) {
+	s, r := newTestRemote(t, nil)
+
+	dir := t.TempDir()
+	require.Nil(t, os.MkdirAll(filepath.Join(dir, "src", "sub"), 0o755))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "src", "a.txt"), []byte("a"), 0o644))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "src", "sub", "b.txt"), []byte("b"), 0o644))
+
+	require.Nil(t, r.UploadFile(filepath.Join(dir, "src", "a.txt"), "/opt/app/a.txt"))
+	content, err := os.ReadFile(filepath.Join(s.Root, "opt", "app", "a.txt"))
+	require.Nil(t, err)
+	require.Equal(t, "a", string(content))
+
+	require.Nil(t, r.UploadDir(filepath.Join(dir, "src"), "/opt/dir"))
+	content, err = os.ReadFile(filepath.Join(s.Root, "opt", "dir", "sub", "b.txt"))
+	require.Nil(t, err)
+	require.Equal(t, "b", string(content))
+
+	fi, err := r.Stat("/opt/dir/sub")
+	require.Nil(t, err)
+	require.True(t, fi.IsDir())
+
+	_, err = r.Stat("/opt/missing")
+	require.True(t, os.IsNotExist(err))
+
+	require.Nil(t, r.DownloadFile("/opt/app/a.txt", filepath.Join(dir, "downloaded.txt")))
+	content, err = os.ReadFile(filepath.Join(dir, "downloaded.txt"))
+	require.Nil(t, err)
+	require.Equal(t, "a", string(content))
+}
+
+func TestRemoteChecksum(t *testing.T) {
+	const sum = "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"
+	var cmds []string
+	_, r := newTestRemote(t, func(c *sshtest.Command) int {
+		cmds = append(cmds, c.Cmd)
+		if strings.Contains(c.Cmd, "missing") {
+			_, _ = io.WriteString(c.Stderr, "shasum: /opt/missing: No such file or directory\n")
+			return 1
+		}
+		_, _ = io.WriteString(c.Stdout, sum+"  /opt/it's a.txt\n")
+		return 0
+	})
+
+	got, err := r.Checksum("/opt/it's a.txt")
+	require.Nil(t, err)
+	require.Equal(t, sum, got)
+
+	local := filepath.Join(t.TempDir(), "a.txt")
+	require.Nil(t, os.WriteFile(local, []byte("a"), 0o644))
+	localSum, err := remote.FileChecksum(local)
+	require.Nil(t, err)
+	require.Equal(t, sum, localSum)
+
+	_, err = r.Checksum("/opt/missing")
+	require.ErrorContains(t, err, "No such file or directory")
+
+	require.Equal(t, []string{
+		`sha256sum -- '/opt/it'\''s a.txt' 2>/dev/null || shasum -a 256 -- '/opt/it'\''s a.txt'`,
+		`sha256sum -- '/opt/missing' 2>/dev/null || shasum -a 256 -- '/opt/missing'`,
+	}, cmds)
+}
+
+func TestRemoteExecuteCommandWithPty(t *testing.T) {
+	var pty *sshtest.Pty
+	_, r := newTestRemote(t, func(c *sshtest.Command) int {
+		pty = c.Pty
+		_, _ = io.WriteString(c.Stdout, "line 1\n")
+		_, _ = io.WriteString(c.Stderr, "line 2\n")
+		return 0
+	})
+
+	modes, err := remote.ParseTerminalModes(map[string]uint32{"echo": 1})
+	require.Nil(t, err)
+
+	_, stdout, stderr, err := r.ExecuteCommand("top -b -n 1", remote.WithPty(remote.PtyConfig{Width: 120, Modes: modes}))
+	require.Nil(t, err)
+	require.Equal(t, "line 1\nline 2\n", stdout)
+	require.Equal(t, "", stderr)
+	require.Equal(t, &sshtest.Pty{Term: remote.DefaultPtyTerm, Width: 120, Height: remote.DefaultPtyHeight}, pty)
+
+	_, err = remote.ParseTerminalModes(map[string]uint32{"NOPE": 1})
+	require.NotNil(t, err)
+}
+
+func TestRemoteExecuteCommandWithEnv(t *testing.T) {
+	var cmd *sshtest.Command
+	s, r := newTestRemote(t, func(c *sshtest.Command) int {
+		cmd = c
+		return 0
+	})
+	s.AcceptEnv = []string{"APP_*"}
+
+	_, _, _, err := r.ExecuteCommand("./run.sh", remote.WithEnv(map[string]string{"APP_PORT": "80"}), remote.WithDir("/opt/app"))
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{"APP_PORT": "80"}, cmd.Env)
+	require.Equal(t, "cd -- '/opt/app' || exit $?\n./run.sh", cmd.Cmd)
+
+	_, _, _, err = r.ExecuteCommand("./run.sh", remote.WithEnv(map[string]string{"APP_PORT": "80", "FOO": "it's"}))
+	require.Nil(t, err)
+	require.Equal(t, "export APP_PORT='80'\nexport FOO='it'\\''s'\n./run.sh", cmd.Cmd)
+}
+
+func TestRemoteExecuteCommandWithStdin(t *testing.T) {
+	_, r := newTestRemote(t, func(c *sshtest.Command) int {
+		_, _ = io.Copy(c.Stdout, c.Stdin)
+		return 0
+	})
+
+	_, stdout, _, err := r.ExecuteCommand("cat", remote.WithStdin(strings.NewReader("select 1;\n")))
+	require.Nil(t, err)
+	require.Equal(t, "select 1;\n", stdout)
+}
+
+func TestRemoteExecuteCommandTimeout(t *testing.T) {
+	_, r := newTestRemote(t, func(c *sshtest.Command) int {
+		time.Sleep(500 * time.Millisecond)
+		return 0
+	})
+
+	_, _, _, err := r.ExecuteCommand("sleep 10", remote.WithTimeout(50*time.Millisecond))
+	require.ErrorIs(t, err, remote.ErrCommandTimeout)
+
+	exitStatus, _, _, err := remote.NewLocal().ExecuteCommand("sleep 10", remote.WithTimeout(50*time.Millisecond))
+	require.ErrorIs(t, err, remote.ErrCommandTimeout)
+	require.Equal(t, 0, exitStatus)
+}
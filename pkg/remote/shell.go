@@ -0,0 +1,69 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package remote
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// envNameRegexp matches valid POSIX environment variable names.
+var envNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateEnvName returns an error if name cannot be used as an environment variable.
+func ValidateEnvName(name string) error {
+	if !envNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid environment variable name: %q", name)
+	}
+	return nil
+}
+
+// ShellQuote quotes s for use as a single word in a POSIX shell command.
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// sortedKeys returns the keys of env in sorted order.
+func sortedKeys(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// commandPrefix returns the shell lines that export env and change to dir
+// before a command runs. Either may be empty.
+func commandPrefix(env map[string]string, dir string) string {
+	sb := strings.Builder{}
+	for _, k := range sortedKeys(env) {
+		sb.WriteString(fmt.Sprintf("export %s=%s\n", k, ShellQuote(env[k])))
+	}
+	if dir != "" {
+		sb.WriteString(fmt.Sprintf("cd -- %s || exit $?\n", ShellQuote(dir)))
+	}
+	return sb.String()
+}
+
+// becomeCommand returns cmd wrapped to run as root through "sudo -n".
+func becomeCommand(cmd string) string {
+	return "sudo -n -- sh -c " + ShellQuote(cmd)
+}
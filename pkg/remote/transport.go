@@ -0,0 +1,53 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package remote
+
+import "os"
+
+// Transport is a connection to a host that operations are executed through.
+// Remote implements it over SSH/SFTP, Local on the control machine and Fake in memory.
+type Transport interface {
+	// ExecuteCommand runs cmd and returns its exit status, stdout and stderr.
+	// A non-zero exit status is not an error.
+	ExecuteCommand(cmd string, opts ...ExecOption) (int, string, string, error)
+	// UploadFile copies a local file to remotePath, creating parent directories.
+	UploadFile(localPath, remotePath string) error
+	// UploadDir copies a local directory recursively to remoteDir.
+	UploadDir(localDir, remoteDir string) error
+	// DownloadFile copies remotePath to a local file.
+	DownloadFile(remotePath, localPath string) error
+	// Checksum returns the hex encoded SHA-256 checksum of the file at
+	// remotePath, computed on the host without transferring the file.
+	Checksum(remotePath string) (string, error)
+	// Stat returns the file info of remotePath.
+	Stat(remotePath string) (os.FileInfo, error)
+	// Chmod changes the permission bits of remotePath.
+	Chmod(remotePath string, mode os.FileMode) error
+	// Close releases the connection.
+	Close() error
+}
+
+// Dialer opens a Transport to the given Host.
+type Dialer func(h Host) (Transport, error)
+
+// Compile-time checks that the built-in transports implement Transport.
+var (
+	_ Transport = (*Remote)(nil)
+	_ Transport = (*Local)(nil)
+	_ Transport = (*Fake)(nil)
+)
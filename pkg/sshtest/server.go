@@ -0,0 +1,314 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package sshtest provides an in-process SSH/SFTP server for end-to-end tests.
+//
+// The server listens on a random local port, serves SFTP from a temporary
+// directory and answers exec requests with a Handler instead of a real shell:
+//
+//	s := sshtest.NewServer(t, func(c *sshtest.Command) int {
+//		_, _ = io.WriteString(c.Stdout, "hello\n")
+//		return 0
+//	})
+//	r, err := remote.New(s.Host())
+package sshtest
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"net"
+	"path"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/cqroot/minop/pkg/remote"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// Credentials accepted by every Server.
+const (
+	User     = "minop"
+	Password = "minop"
+)
+
+// Command describes a command received by a Server.
+type Command struct {
+	Cmd    string
+	Env    map[string]string
+	Pty    *Pty
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Pty describes the pseudo terminal requested for a command. Like a real
+// terminal, output written to Stdout or Stderr is merged and "\n" is sent as "\r\n".
+type Pty struct {
+	Term   string
+	Width  int
+	Height int
+}
+
+// Handler runs a command received by a Server and returns its exit status.
+type Handler func(c *Command) int
+
+// Server is an SSH server with an SFTP subsystem, running in the test process.
+type Server struct {
+	// Addr is the address the server listens on, in the form "127.0.0.1:<port>".
+	Addr string
+	// Root is the temporary directory that backs the SFTP subsystem.
+	// The remote path "/etc/app.conf" is stored at Root/etc/app.conf.
+	Root string
+	// AcceptEnv lists the environment variables accepted through "env"
+	// requests, like AcceptEnv in sshd_config. Patterns use path.Match syntax.
+	// All other variables are refused. Set it before connecting.
+	AcceptEnv []string
+
+	handler  Handler
+	config   *ssh.ServerConfig
+	listener net.Listener
+	wg       sync.WaitGroup
+}
+
+// NewServer starts a Server that answers commands with handler.
+// A nil handler makes every command succeed with empty output.
+// The server is closed when the test finishes.
+func NewServer(t testing.TB, handler Handler) *Server {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("sshtest: generate host key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("sshtest: create host key signer: %v", err)
+	}
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == User && string(password) == Password {
+				return nil, nil
+			}
+			return nil, ssh.ErrNoAuth
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("sshtest: listen: %v", err)
+	}
+
+	s := &Server{
+		Addr:     listener.Addr().String(),
+		Root:     t.TempDir(),
+		handler:  handler,
+		config:   config,
+		listener: listener,
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// Host returns the Host that connects to the server.
+func (s *Server) Host() remote.Host {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return remote.Host{
+		User:     User,
+		Password: Password,
+		Address:  addr.IP.String(),
+		Port:     addr.Port,
+	}
+}
+
+// HostLine returns the host line that connects to the server, for use in config files.
+func (s *Server) HostLine() string {
+	h := s.Host()
+	return h.User + ":" + h.Password + "@" + h.Address + ":" + strconv.Itoa(h.Port)
+}
+
+// Close stops the server and waits for open connections to finish.
+func (s *Server) Close() {
+	_ = s.listener.Close()
+	s.wg.Wait()
+}
+
+// serve accepts connections until the listener is closed.
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serveConn(conn)
+		}()
+	}
+}
+
+// serveConn performs the SSH handshake and serves the session channels of a connection.
+func (s *Server) serveConn(conn net.Conn) {
+	sconn, chans, reqs, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	defer func() { _ = sconn.Close() }()
+	go ssh.DiscardRequests(reqs)
+
+	var wg sync.WaitGroup
+	for newCh := range chans {
+		if newCh.ChannelType() != "session" {
+			_ = newCh.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+
+		ch, chReqs, err := newCh.Accept()
+		if err != nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveSession(ch, chReqs)
+		}()
+	}
+	wg.Wait()
+}
+
+// serveSession handles the requests of a session channel.
+func (s *Server) serveSession(ch ssh.Channel, reqs <-chan *ssh.Request) {
+	env := make(map[string]string)
+	var pty *Pty
+
+	for req := range reqs {
+		switch req.Type {
+		case "env":
+			var payload struct{ Name, Value string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			if !s.acceptEnv(payload.Name) {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			env[payload.Name] = payload.Value
+			_ = req.Reply(true, nil)
+
+		case "pty-req":
+			var payload struct {
+				Term                         string
+				Columns, Rows, Width, Height uint32
+				Modes                        string
+			}
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			pty = &Pty{Term: payload.Term, Width: int(payload.Columns), Height: int(payload.Rows)}
+			_ = req.Reply(true, nil)
+
+		case "exec":
+			var payload struct{ Command string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			_ = req.Reply(true, nil)
+
+			c := &Command{
+				Cmd:    payload.Command,
+				Env:    env,
+				Pty:    pty,
+				Stdin:  ch,
+				Stdout: ch,
+				Stderr: ch.Stderr(),
+			}
+			if pty != nil {
+				c.Stdout = crlfWriter{ch}
+				c.Stderr = c.Stdout
+			}
+			go s.exec(ch, c)
+
+		case "subsystem":
+			var payload struct{ Name string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil || payload.Name != "sftp" {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			_ = req.Reply(true, nil)
+			go func() {
+				server := sftp.NewRequestServer(ch, newRootHandlers(s.Root))
+				_ = server.Serve()
+				_ = server.Close()
+			}()
+
+		default:
+			_ = req.Reply(false, nil)
+		}
+	}
+}
+
+// acceptEnv reports whether the environment variable name matches AcceptEnv.
+func (s *Server) acceptEnv(name string) bool {
+	for _, pattern := range s.AcceptEnv {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// crlfWriter translates "\n" to "\r\n" like a terminal with ONLCR set.
+type crlfWriter struct {
+	w io.Writer
+}
+
+// Write writes p with every "\n" replaced by "\r\n".
+func (w crlfWriter) Write(p []byte) (int, error) {
+	if _, err := w.w.Write(bytes.ReplaceAll(p, []byte("\n"), []byte("\r\n"))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// exec runs a command with the server's handler and reports its exit status.
+func (s *Server) exec(ch ssh.Channel, c *Command) {
+	status := 0
+	if s.handler != nil {
+		status = s.handler(c)
+	}
+
+	_, _ = ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(status)}))
+	_ = ch.Close()
+}
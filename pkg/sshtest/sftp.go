@@ -0,0 +1,141 @@
+/*
+Copyright (C) 2025 Keith Chu <cqroot@outlook.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package sshtest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/pkg/sftp"
+)
+
+// rootHandler serves SFTP requests from a local directory, mapping the remote
+// root "/" to that directory.
+type rootHandler struct {
+	root string
+}
+
+// newRootHandlers creates SFTP handlers that serve files below root.
+func newRootHandlers(root string) sftp.Handlers {
+	h := rootHandler{root: root}
+	return sftp.Handlers{
+		FileGet:  h,
+		FilePut:  h,
+		FileCmd:  h,
+		FileList: h,
+	}
+}
+
+// localPath converts a remote path to the local path below the root.
+func (h rootHandler) localPath(p string) string {
+	return filepath.Join(h.root, filepath.FromSlash(path.Clean("/"+p)))
+}
+
+// Fileread opens a file for reading.
+func (h rootHandler) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	return os.Open(h.localPath(r.Filepath))
+}
+
+// Filewrite opens a file for writing.
+func (h rootHandler) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	flags := os.O_WRONLY
+	pflags := r.Pflags()
+	if pflags.Creat {
+		flags |= os.O_CREATE
+	}
+	if pflags.Trunc {
+		flags |= os.O_TRUNC
+	}
+	if pflags.Excl {
+		flags |= os.O_EXCL
+	}
+	return os.OpenFile(h.localPath(r.Filepath), flags, 0o644)
+}
+
+// Filecmd handles file system changes.
+func (h rootHandler) Filecmd(r *sftp.Request) error {
+	p := h.localPath(r.Filepath)
+
+	switch r.Method {
+	case "Setstat":
+		if r.AttrFlags().Permissions {
+			return os.Chmod(p, r.Attributes().FileMode().Perm())
+		}
+		return nil
+	case "Rename", "PosixRename":
+		return os.Rename(p, h.localPath(r.Target))
+	case "Rmdir", "Remove":
+		return os.Remove(p)
+	case "Mkdir":
+		return os.Mkdir(p, 0o755)
+	case "Symlink":
+		return os.Symlink(r.Filepath, h.localPath(r.Target))
+	case "Link":
+		return os.Link(p, h.localPath(r.Target))
+	}
+	return fmt.Errorf("unsupported method: %s", r.Method)
+}
+
+// Filelist lists directories and stats files.
+func (h rootHandler) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	p := h.localPath(r.Filepath)
+
+	switch r.Method {
+	case "List":
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return nil, err
+		}
+
+		infos := make([]os.FileInfo, 0, len(entries))
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil {
+				return nil, err
+			}
+			infos = append(infos, info)
+		}
+		return listerAt(infos), nil
+	case "Stat":
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		return listerAt{info}, nil
+	}
+	return nil, fmt.Errorf("unsupported method: %s", r.Method)
+}
+
+// listerAt implements sftp.ListerAt for a fixed list of file infos.
+type listerAt []os.FileInfo
+
+// ListAt copies the entries starting at offset into ls.
+func (l listerAt) ListAt(ls []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+
+	n := copy(ls, l[offset:])
+	if n < len(ls) {
+		return n, io.EOF
+	}
+	return n, nil
+}